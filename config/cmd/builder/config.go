@@ -1,6 +1,9 @@
 package builder
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	env "service-worker-sqs-s3-postgres/dataproviders/utils"
 )
 
@@ -9,13 +12,44 @@ type Configuration struct {
 	Port                 int
 	ApplicationID        string
 	LogLevel             string
+	ShutdownTimeout      int
 	Region               string
 	AccessKey            string
 	SecretKey            string
 	SQSUrl               string
 	SQSMaxMessages       int
 	SQSVisibilityTimeout int
+	SQSDeadLetterUrl     string
+	SQSQueues            []QueueConfiguration
+	SQSHeartbeatInterval int
+	SQSBackoffBase       int
+	SQSBackoffMax        int
+	SQSMaxAttempts       int
+	SQSGiveUpPolicy      string
+	WorkerDownloaders    int
+	WorkerInserters      int
+	WorkerQueueSize      int
+	RejectPolicy         string
+	RejectThreshold      int
+	ColumnMapping        string
+	Pipelines            string
+	ChunkSize            int
+	LedgerClaimTimeout   int
+	ValidationRules      string
+	RejectsReportBucket  string
+	RejectsReportPrefix  string
+	RejectsReportFormat  string
+	CSVDialects          string
+	XLSXSheet            string
+	XLSXSkipRows         int
+	XLSXMaxMB            int
+	FixedWidthLayout     string
+	ParserRoutes         string
+	ParserTag            string
 	S3Bucket             string
+	DownloadMode         string
+	SpoolMaxMB           int
+	DiskMinFreeMB        int
 	DBPort               string
 	DBHost               string
 	DBName               string
@@ -23,6 +57,16 @@ type Configuration struct {
 	DBPassword           string
 }
 
+// QueueConfiguration represents parameters of a consumed queue.
+type QueueConfiguration struct {
+	URL               string   `json:"url"`
+	MaxMessages       int      `json:"maxMessages"`
+	VisibilityTimeout int      `json:"visibilityTimeout"`
+	DeadLetterUrl     string   `json:"deadLetterUrl"`
+	Buckets           []string `json:"buckets"`
+	Pipeline          string   `json:"pipeline"`
+}
+
 // LoadConfig get all the configuration variables for the implemented usecases.
 func LoadConfig() (*Configuration, error) {
 	applicationID, err := env.GetString("APPLICATION_ID")
@@ -40,6 +84,11 @@ func LoadConfig() (*Configuration, error) {
 		return nil, err
 	}
 
+	shutdownTimeout, err := env.GetIntDefault("SHUTDOWN_TIMEOUT", 30)
+	if err != nil {
+		return nil, err
+	}
+
 	access, err := env.GetString("AWS_ACCESS_KEY")
 	if err != nil {
 		return nil, err
@@ -55,26 +104,133 @@ func LoadConfig() (*Configuration, error) {
 		return nil, err
 	}
 
-	sqsUrl, err := env.GetString("AWS_SQS_URL")
+	sqsUrl := env.GetStringDefault("AWS_SQS_URL", "")
+
+	sqsMaxMessages, err := env.GetIntDefault("AWS_SQS_MAX_MESSAGES", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsVisibilityTimeout, err := env.GetIntDefault("AWS_SQS_VISIBILITY_TIMEOUT", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsDeadLetterUrl := env.GetStringDefault("AWS_SQS_DLQ_URL", "")
+
+	sqsQueues, err := loadQueues(env.GetStringDefault("AWS_SQS_QUEUES", ""), QueueConfiguration{
+		URL:               sqsUrl,
+		MaxMessages:       sqsMaxMessages,
+		VisibilityTimeout: sqsVisibilityTimeout,
+		DeadLetterUrl:     sqsDeadLetterUrl,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sqsHeartbeatInterval, err := env.GetIntDefault("AWS_SQS_HEARTBEAT_INTERVAL", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsBackoffBase, err := env.GetIntDefault("AWS_SQS_BACKOFF_BASE", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsBackoffMax, err := env.GetIntDefault("AWS_SQS_BACKOFF_MAX", 900)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsMaxAttempts, err := env.GetIntDefault("AWS_SQS_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+
+	sqsGiveUpPolicy := env.GetStringDefault("AWS_SQS_GIVE_UP_POLICY", "deadletter")
+
+	workerDownloaders, err := env.GetIntDefault("WORKER_DOWNLOADERS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	workerInserters, err := env.GetIntDefault("WORKER_INSERTERS", 4)
+	if err != nil {
+		return nil, err
+	}
+
+	workerQueueSize, err := env.GetIntDefault("WORKER_QUEUE_SIZE", sqsMaxMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	rejectPolicy := env.GetStringDefault("REJECT_POLICY", "skip")
+
+	rejectThreshold, err := env.GetIntDefault("REJECT_THRESHOLD", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	columnMapping := env.GetStringDefault("COLUMN_MAPPING", "")
+
+	pipelines := env.GetStringDefault("PIPELINES", "")
+
+	chunkSize, err := env.GetIntDefault("CHUNK_SIZE", 1000)
 	if err != nil {
 		return nil, err
 	}
 
-	sqsMaxMessages, err := env.GetInt("AWS_SQS_MAX_MESSAGES")
+	ledgerClaimTimeout, err := env.GetIntDefault("LEDGER_CLAIM_TIMEOUT", 3600)
 	if err != nil {
 		return nil, err
 	}
 
-	sqsVisibilityTimeout, err := env.GetInt("AWS_SQS_VISIBILITY_TIMEOUT")
+	validationRules := env.GetStringDefault("VALIDATION_RULES", "")
+
+	rejectsReportBucket := env.GetStringDefault("REJECTS_REPORT_BUCKET", "")
+
+	rejectsReportPrefix := env.GetStringDefault("REJECTS_REPORT_PREFIX", "rejected/")
+
+	rejectsReportFormat := env.GetStringDefault("REJECTS_REPORT_FORMAT", "csv")
+
+	csvDialects := env.GetStringDefault("CSV_DIALECTS", "")
+
+	xlsxSheet := env.GetStringDefault("XLSX_SHEET", "")
+
+	xlsxSkipRows, err := env.GetIntDefault("XLSX_SKIP_ROWS", 0)
 	if err != nil {
 		return nil, err
 	}
 
+	xlsxMaxMB, err := env.GetIntDefault("XLSX_MAX_MB", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	fixedWidthLayout := env.GetStringDefault("FIXED_WIDTH_LAYOUT", "")
+
+	parserRoutes := env.GetStringDefault("PARSER_ROUTES", "")
+
+	parserTag := env.GetStringDefault("PARSER_TAG", "")
+
 	s3Bucket, err := env.GetString("AWS_S3_BUCKET")
 	if err != nil {
 		return nil, err
 	}
 
+	downloadMode := env.GetStringDefault("DOWNLOAD_MODE", "stream")
+
+	spoolMaxMB, err := env.GetIntDefault("SPOOL_MAX_MB", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	diskMinFreeMB, err := env.GetIntDefault("DISK_MIN_FREE_MB", 512)
+	if err != nil {
+		return nil, err
+	}
+
 	dbPort, err := env.GetString("DB_PORT")
 	if err != nil {
 		return nil, err
@@ -104,13 +260,44 @@ func LoadConfig() (*Configuration, error) {
 		Port:                 port,
 		ApplicationID:        applicationID,
 		LogLevel:             loglevel,
+		ShutdownTimeout:      shutdownTimeout,
 		AccessKey:            access,
 		SecretKey:            secret,
 		Region:               region,
 		SQSUrl:               sqsUrl,
 		SQSMaxMessages:       sqsMaxMessages,
 		SQSVisibilityTimeout: sqsVisibilityTimeout,
+		SQSDeadLetterUrl:     sqsDeadLetterUrl,
+		SQSQueues:            sqsQueues,
+		SQSHeartbeatInterval: sqsHeartbeatInterval,
+		SQSBackoffBase:       sqsBackoffBase,
+		SQSBackoffMax:        sqsBackoffMax,
+		SQSMaxAttempts:       sqsMaxAttempts,
+		SQSGiveUpPolicy:      sqsGiveUpPolicy,
+		WorkerDownloaders:    workerDownloaders,
+		WorkerInserters:      workerInserters,
+		WorkerQueueSize:      workerQueueSize,
+		RejectPolicy:         rejectPolicy,
+		RejectThreshold:      rejectThreshold,
+		ColumnMapping:        columnMapping,
+		Pipelines:            pipelines,
+		ChunkSize:            chunkSize,
+		LedgerClaimTimeout:   ledgerClaimTimeout,
+		ValidationRules:      validationRules,
+		RejectsReportBucket:  rejectsReportBucket,
+		RejectsReportPrefix:  rejectsReportPrefix,
+		RejectsReportFormat:  rejectsReportFormat,
+		CSVDialects:          csvDialects,
+		XLSXSheet:            xlsxSheet,
+		XLSXSkipRows:         xlsxSkipRows,
+		XLSXMaxMB:            xlsxMaxMB,
+		FixedWidthLayout:     fixedWidthLayout,
+		ParserRoutes:         parserRoutes,
+		ParserTag:            parserTag,
 		S3Bucket:             s3Bucket,
+		DownloadMode:         downloadMode,
+		SpoolMaxMB:           spoolMaxMB,
+		DiskMinFreeMB:        diskMinFreeMB,
 		DBPort:               dbPort,
 		DBHost:               dbHost,
 		DBName:               dbName,
@@ -118,3 +305,38 @@ func LoadConfig() (*Configuration, error) {
 		DBPassword:           dbPassword,
 	}, nil
 }
+
+// loadQueues returns the queues listed as a JSON array in raw. Missing settings of every queue are taken from
+// defaults, and when raw is empty the only queue is defaults itself.
+func loadQueues(raw string, defaults QueueConfiguration) ([]QueueConfiguration, error) {
+	if raw == "" {
+		if defaults.URL == "" {
+			return nil, errors.New("env var AWS_SQS_URL or AWS_SQS_QUEUES not found")
+		}
+		return []QueueConfiguration{defaults}, nil
+	}
+
+	queues := make([]QueueConfiguration, 0)
+	if err := json.Unmarshal([]byte(raw), &queues); err != nil {
+		return nil, fmt.Errorf("env var AWS_SQS_QUEUES must be a json array: %w", err)
+	}
+	if len(queues) == 0 {
+		return nil, errors.New("env var AWS_SQS_QUEUES has no queues")
+	}
+
+	for i := range queues {
+		if queues[i].URL == "" {
+			return nil, fmt.Errorf("env var AWS_SQS_QUEUES: queue %d has no url", i)
+		}
+		if queues[i].MaxMessages == 0 {
+			queues[i].MaxMessages = defaults.MaxMessages
+		}
+		if queues[i].VisibilityTimeout == 0 {
+			queues[i].VisibilityTimeout = defaults.VisibilityTimeout
+		}
+		if queues[i].DeadLetterUrl == "" {
+			queues[i].DeadLetterUrl = defaults.DeadLetterUrl
+		}
+	}
+	return queues, nil
+}
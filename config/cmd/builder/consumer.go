@@ -9,8 +9,15 @@ import (
 	"service-worker-sqs-s3-postgres/dataproviders/awss3/downloader"
 	"service-worker-sqs-s3-postgres/dataproviders/awssqs"
 	"service-worker-sqs-s3-postgres/dataproviders/consumer"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/rejectreport"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/validation"
+	"service-worker-sqs-s3-postgres/dataproviders/postgres"
 	rfiledata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/filedata"
+	rledger "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/ledger"
 	rmetadata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/metadata"
+	rrejected "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/rejected"
+	"time"
 )
 
 // NewConsumer define all usecases to instantiate SQS.
@@ -19,27 +26,117 @@ func NewConsumer(logger *zap.SugaredLogger,
 	sessSQS *session.Session,
 	sessS3 *session.Session,
 	rfd rfiledata.IFileDataRepository,
-	rmd rmetadata.IMetaDataRepository) (domain.Source, error) {
+	rmd rmetadata.IMetaDataRepository,
+	rl rledger.ILedgerRepository,
+	rr rrejected.IRejectedRepository,
+	tx postgres.ITransactor,
+	pipelines map[string]consumer.Pipeline) (domain.Source, error) {
 
 	s3, err := awss3.NewS3Client(sessS3, config.S3Bucket)
 	if err != nil {
 		return nil, fmt.Errorf("error awssqs.NewSQSClient: %w", err)
 	}
 
-	sqs, err := awssqs.NewSQSClient(sessSQS, config.SQSUrl, config.SQSMaxMessages, config.SQSVisibilityTimeout)
+	queues := make([]consumer.Queue, 0, len(config.SQSQueues))
+	for _, q := range config.SQSQueues {
+		queue, err := newQueue(sessSQS, q)
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, queue)
+	}
+
+	giveUp, err := consumer.ParseGiveUpPolicy(config.SQSGiveUpPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("error awssqs.NewSQSClient: %w", err)
+		return nil, fmt.Errorf("error consumer.ParseGiveUpPolicy: %w", err)
+	}
+
+	rejectMode, err := record.ParsePolicyMode(config.RejectPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("error record.ParsePolicyMode: %w", err)
+	}
+
+	rules, err := validation.Parse(config.ValidationRules)
+	if err != nil {
+		return nil, fmt.Errorf("error validation.Parse: %w", err)
 	}
 
-	download, err := downloader.NewDownloader(s3, logger)
+	reportFormat, err := rejectreport.ParseFormat(config.RejectsReportFormat)
+	if err != nil {
+		return nil, fmt.Errorf("error rejectreport.ParseFormat: %w", err)
+	}
+
+	parsers, err := newParsers(config)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := consumer.Options{
+		HeartbeatInterval: time.Duration(config.SQSHeartbeatInterval) * time.Second,
+		Backoff: consumer.Backoff{
+			Base:        time.Duration(config.SQSBackoffBase) * time.Second,
+			Max:         time.Duration(config.SQSBackoffMax) * time.Second,
+			MaxAttempts: config.SQSMaxAttempts,
+			GiveUp:      giveUp,
+		},
+		Downloaders:  config.WorkerDownloaders,
+		Inserters:    config.WorkerInserters,
+		QueueSize:    config.WorkerQueueSize,
+		ChunkSize:    config.ChunkSize,
+		ClaimTimeout: time.Duration(config.LedgerClaimTimeout) * time.Second,
+		Parsers:      parsers,
+		Pipelines:    pipelines,
+		Rules:        rules,
+		Reports: rejectreport.Options{
+			Bucket: config.RejectsReportBucket,
+			Prefix: config.RejectsReportPrefix,
+			Format: reportFormat,
+		},
+		Rejects: record.Policy{
+			Mode:        rejectMode,
+			MaxRejected: config.RejectThreshold,
+		},
+	}
+
+	downloadMode, err := downloader.ParseMode(config.DownloadMode)
+	if err != nil {
+		return nil, fmt.Errorf("error downloader.ParseMode: %w", err)
+	}
+
+	download, err := downloader.NewDownloader(s3, logger, downloader.Options{
+		Mode:          downloadMode,
+		MaxSpoolBytes: int64(config.SpoolMaxMB) << 20,
+		MinFreeBytes:  int64(config.DiskMinFreeMB) << 20,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("error downloader.NewDownloader: %w", err)
 	}
 
-	source, err := consumer.New(sqs, download, logger, config.SQSMaxMessages, rfd, rmd)
+	source, err := consumer.New(queues, download, logger, rfd, rmd, rl, rr, tx, opts)
 	if err != nil {
 		return nil, fmt.Errorf("error consumer.New: %w", err)
 	}
 
 	return source, nil
 }
+
+// newQueue instantiates the SQS clients of a consumed queue.
+func newQueue(sessSQS *session.Session, config QueueConfiguration) (consumer.Queue, error) {
+	sqs, err := awssqs.NewSQSClient(sessSQS, config.URL, config.MaxMessages, config.VisibilityTimeout)
+	if err != nil {
+		return consumer.Queue{}, fmt.Errorf("error awssqs.NewSQSClient: %w", err)
+	}
+
+	queue := consumer.Queue{
+		Client:   sqs,
+		Buckets:  config.Buckets,
+		Pipeline: config.Pipeline,
+	}
+	if config.DeadLetterUrl != "" {
+		queue.DeadLetter, err = awssqs.NewSQSClient(sessSQS, config.DeadLetterUrl, config.MaxMessages, config.VisibilityTimeout)
+		if err != nil {
+			return consumer.Queue{}, fmt.Errorf("error awssqs.NewSQSClient: %w", err)
+		}
+	}
+	return queue, nil
+}
@@ -1,9 +1,17 @@
 package builder
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer"
 	"service-worker-sqs-s3-postgres/dataproviders/postgres"
+	rfiledata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/filedata"
 )
 
+// tableName matches the names accepted for the tables of pipelines.
+var tableName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
 // NewDB defines all configurations to instantiate a postgres client.
 func NewDB(config *Configuration) (*postgres.ClientDB, error) {
 	db := postgres.NewDBClient(config.DBHost, config.DBUsername, config.DBPassword, config.DBName, config.DBPort)
@@ -11,3 +19,33 @@ func NewDB(config *Configuration) (*postgres.ClientDB, error) {
 
 	return db, err
 }
+
+// NewPipelines instantiates the pipelines queues can target besides consumer.DefaultPipeline, described in
+// config as a JSON object from the name of each pipeline to the table its rows are inserted in.
+func NewPipelines(config *Configuration, db *postgres.ClientDB) (map[string]consumer.Pipeline, error) {
+	pipelines := make(map[string]consumer.Pipeline)
+	if config.Pipelines == "" {
+		return pipelines, nil
+	}
+
+	tables := make(map[string]string)
+	if err := json.Unmarshal([]byte(config.Pipelines), &tables); err != nil {
+		return nil, fmt.Errorf("env var PIPELINES must be a json object: %w", err)
+	}
+
+	for name, table := range tables {
+		if name == consumer.DefaultPipeline {
+			return nil, fmt.Errorf("env var PIPELINES: pipeline %q is reserved", name)
+		}
+		if !tableName.MatchString(table) {
+			return nil, fmt.Errorf("env var PIPELINES: pipeline %q has an invalid table %q", name, table)
+		}
+
+		repository := rfiledata.NewFileDataTableRepository(db, table)
+		if err := repository.Migrate(); err != nil {
+			return nil, fmt.Errorf("error migrating table %s of pipeline %s: %w", table, name, err)
+		}
+		pipelines[name] = consumer.Pipeline{FileData: repository}
+	}
+	return pipelines, nil
+}
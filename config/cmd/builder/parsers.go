@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"fmt"
+	"os"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/csvreader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/fixedwidthreader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/jsonreader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parquetreader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/xlsxreader"
+)
+
+// newParsers registers the parser of every supported format, with csv as the default one.
+func newParsers(config *Configuration) (*parser.Registry, error) {
+	columns, err := mapping.Parse(config.ColumnMapping)
+	if err != nil {
+		return nil, fmt.Errorf("error mapping.Parse: %w", err)
+	}
+
+	dialects, err := csvreader.ParseDialects(config.CSVDialects)
+	if err != nil {
+		return nil, fmt.Errorf("error csvreader.ParseDialects: %w", err)
+	}
+
+	var layout fixedwidthreader.Layout
+	if config.FixedWidthLayout != "" {
+		raw, err := os.ReadFile(config.FixedWidthLayout)
+		if err != nil {
+			return nil, fmt.Errorf("error reading fixed-width layout: %w", err)
+		}
+		if layout, err = fixedwidthreader.ParseLayout(raw); err != nil {
+			return nil, fmt.Errorf("error fixedwidthreader.ParseLayout: %w", err)
+		}
+	}
+
+	registrations := []parser.Registration{
+		{
+			Name:         csvreader.Name,
+			Parser:       csvreader.NewParser(dialects, columns),
+			Extensions:   csvreader.Extensions,
+			ContentTypes: csvreader.ContentTypes,
+		},
+		{
+			Name:         jsonreader.Name,
+			Parser:       jsonreader.NewParser(columns),
+			Extensions:   jsonreader.Extensions,
+			ContentTypes: jsonreader.ContentTypes,
+		},
+		{
+			Name:         parquetreader.Name,
+			Parser:       parquetreader.NewParser(columns),
+			Extensions:   parquetreader.Extensions,
+			ContentTypes: parquetreader.ContentTypes,
+		},
+		{
+			Name: xlsxreader.Name,
+			Parser: xlsxreader.NewParser(xlsxreader.Options{
+				Sheet:    config.XLSXSheet,
+				SkipRows: config.XLSXSkipRows,
+				MaxBytes: int64(config.XLSXMaxMB) << 20,
+			}, columns),
+			Extensions:   xlsxreader.Extensions,
+			ContentTypes: xlsxreader.ContentTypes,
+		},
+	}
+	// fixed-width files can't be split without a layout, so they have no parser of their own without one.
+	if config.FixedWidthLayout != "" {
+		registrations = append(registrations, parser.Registration{
+			Name:       fixedwidthreader.Name,
+			Parser:     fixedwidthreader.NewParser(layout, columns),
+			Extensions: fixedwidthreader.Extensions,
+		})
+	}
+
+	registry := parser.NewRegistry(config.ParserTag)
+	for _, r := range registrations {
+		if err = registry.Register(r); err != nil {
+			return nil, fmt.Errorf("error parser.Register: %w", err)
+		}
+	}
+	if err = registry.SetDefault(csvreader.Name); err != nil {
+		return nil, fmt.Errorf("error parser.SetDefault: %w", err)
+	}
+
+	routes, err := parser.ParseRoutes(config.ParserRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("error parser.ParseRoutes: %w", err)
+	}
+	for _, route := range routes {
+		if err = registry.Route(route); err != nil {
+			return nil, fmt.Errorf("error parser.Route: %w", err)
+		}
+	}
+	return registry, nil
+}
@@ -16,7 +16,9 @@ func NewSession(config *Configuration, typeSession domain.Session) (*session.Ses
 
 	switch typeSession {
 	case domain.SQS:
-		sessionConfig.Endpoint = aws.String(config.SQSUrl)
+		if config.SQSUrl != "" {
+			sessionConfig.Endpoint = aws.String(config.SQSUrl)
+		}
 		sessionConfig.MaxRetries = aws.Int(3)
 		break
 	case domain.S3:
@@ -1,18 +1,24 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"service-worker-sqs-s3-postgres/config/cmd/builder"
 	"service-worker-sqs-s3-postgres/core/domain"
 	cfiledata "service-worker-sqs-s3-postgres/core/usecases/filedata"
 	cmetadata "service-worker-sqs-s3-postgres/core/usecases/metadata"
+	crejected "service-worker-sqs-s3-postgres/core/usecases/rejected"
 	rfiledata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/filedata"
+	rledger "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/ledger"
 	rmetadata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/metadata"
+	rrejected "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/rejected"
 	"service-worker-sqs-s3-postgres/dataproviders/server"
 	hfiledata "service-worker-sqs-s3-postgres/entrypoints/controllers/filedata"
 	hmetadata "service-worker-sqs-s3-postgres/entrypoints/controllers/metadata"
+	hrejected "service-worker-sqs-s3-postgres/entrypoints/controllers/rejected"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -28,6 +34,9 @@ func main() {
 		logger.Fatalf("error in LoadConfig : %v", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// session aws s3 is initialized
 	sessionS3, err := builder.NewSession(config, domain.S3)
 	if err != nil {
@@ -49,17 +58,27 @@ func main() {
 	// repositories are initialized
 	filedataRepository := rfiledata.NewFileDataRepository(db)
 	metadataRepository := rmetadata.NewMetaDataRepository(db)
+	ledgerRepository := rledger.NewLedgerRepository(db)
+	rejectedRepository := rrejected.NewRejectedRepository(db)
 
 	// use-cases are initialized
 	filedataUseCases := cfiledata.NewFileDataUseCases(filedataRepository)
 	metadataUseCases := cmetadata.NewMetaDataUseCases(metadataRepository)
+	rejectedUseCases := crejected.NewRejectedUseCases(rejectedRepository)
 
 	// controllers are initialized
 	filedataController := hfiledata.NewFileDataController(filedataUseCases)
 	metadataController := hmetadata.NewMetaDataController(metadataUseCases)
+	rejectedController := hrejected.NewRejectedController(rejectedUseCases)
+
+	// pipelines are initialized
+	pipelines, err := builder.NewPipelines(config, db)
+	if err != nil {
+		logger.Fatalf("error in Pipelines : %v", err)
+	}
 
 	// consumer is initialized
-	sqs, err := builder.NewConsumer(logger, config, sessionSQS, sessionS3, filedataRepository, metadataRepository)
+	sqs, err := builder.NewConsumer(logger, config, sessionSQS, sessionS3, filedataRepository, metadataRepository, ledgerRepository, rejectedRepository, db, pipelines)
 	if err != nil {
 		logger.Fatalf("error in SQS : %v", err)
 	}
@@ -69,13 +88,15 @@ func main() {
 	if err != nil {
 		logger.Fatalf("error in Processor : %v", err)
 	}
-	go processor.Start()
+	go processor.Start(ctx)
 
 	// server is initialized
-	srv := server.NewServer(config.Port, filedataController, metadataController)
-	if err = srv.Start(); err != nil {
-		logger.Fatalf("error Starting Server: %v", err)
-	}
+	srv := server.NewServer(config.Port, filedataController, metadataController, rejectedController)
+	go func() {
+		if err := srv.Start(); err != nil {
+			logger.Fatalf("error Starting Server: %v", err)
+		}
+	}()
 
 	// Graceful shutdown
 	sigQuit := make(chan os.Signal, 1)
@@ -83,12 +104,15 @@ func main() {
 	sig := <-sigQuit
 
 	logger.Infof("Shutting down server with signal [%s] ...", sig.String())
-	if err = sqs.Close(); err != nil {
-		logger.Error("error Closing Consumer SQS: %v", err)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(config.ShutdownTimeout)*time.Second)
+	defer cancelShutdown()
+
+	if err = processor.Stop(shutdownCtx); err != nil {
+		logger.Errorf("error Closing Consumer SQS: %v", err)
 	}
 
 	if err = srv.Stop(); err != nil {
-		logger.Error("error Stopping Server: %v", err)
+		logger.Errorf("error Stopping Server: %v", err)
 	}
 
 	logger.Info("service-worker-sqs-s3-postgres ended")
@@ -6,3 +6,14 @@ const (
 	SQS Session = "sqs"
 	S3  Session = "s3"
 )
+
+// Outcome is the result of processing an S3 object.
+type Outcome string
+
+const (
+	Ingested  Outcome = "ingested"
+	Duplicate Outcome = "duplicate"
+	Failed    Outcome = "failed"
+	// Stale is the outcome of events older than the last one ingested for the same object.
+	Stale Outcome = "stale"
+)
@@ -6,6 +6,7 @@ type FileData struct {
 	Message string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:message" json:"message"`
 	Owner   string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:owner" json:"owner"`
 	Date    string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:date" json:"date"`
+	Extra   string `gorm:"NULL;TYPE:TEXT;COLUMN:extra" json:"extra"`
 }
 
 // TableName definition name for table .
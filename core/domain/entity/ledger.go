@@ -0,0 +1,34 @@
+package entity
+
+// ProcessedObject represents the entity.
+type ProcessedObject struct {
+	Bucket      string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:bucket" json:"bucket"`
+	Key         string `gorm:"primaryKey;TYPE:VARCHAR(1024);COLUMN:key" json:"key"`
+	VersionID   string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:versionid" json:"versionid"`
+	Fingerprint string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:fingerprint" json:"fingerprint"`
+	TrackID     string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:trackid" json:"trackid"`
+	// Status is pending while the object is ingested; objects recorded before claims existed are ingested.
+	Status string `gorm:"NOT NULL;DEFAULT:'ingested';TYPE:VARCHAR(20);COLUMN:status" json:"status"`
+	// ClaimedAt is the UTC time of the claim in RFC 3339, so claims compare as text.
+	ClaimedAt   string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:claimedat" json:"claimedat"`
+	ProcessedAt string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:processedat" json:"processedat"`
+}
+
+// TableName definition name for table .
+func (ProcessedObject) TableName() string {
+	return "processed_objects"
+}
+
+// ObjectSequencer represents the entity. The sequencer is stored padded, see utils.PadSequencer.
+type ObjectSequencer struct {
+	Bucket    string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:bucket" json:"bucket"`
+	Key       string `gorm:"primaryKey;TYPE:VARCHAR(1024);COLUMN:key" json:"key"`
+	Sequencer string `gorm:"NOT NULL;TYPE:VARCHAR(200);COLUMN:sequencer" json:"sequencer"`
+	TrackID   string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:trackid" json:"trackid"`
+	UpdatedAt string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:updatedat" json:"updatedat"`
+}
+
+// TableName definition name for table .
+func (ObjectSequencer) TableName() string {
+	return "object_sequencers"
+}
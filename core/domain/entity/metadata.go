@@ -2,11 +2,22 @@ package entity
 
 // MetaData represents the entity.
 type MetaData struct {
-	TrackID  string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:trackid" json:"trackid"`
-	Bucket   string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:bucket" json:"bucket"`
-	FileName string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:filename" json:"filename"`
-	Key      string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:key" json:"key"`
-	Size     int64  `gorm:"NULL;TYPE:INT;COLUMN:size" json:"size"`
+	TrackID       string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:trackid" json:"trackid"`
+	ParentTrackID string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:parenttrackid" json:"parenttrackid"`
+	Bucket        string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:bucket" json:"bucket"`
+	FileName      string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:filename" json:"filename"`
+	Key           string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:key" json:"key"`
+	Size          int64  `gorm:"NULL;TYPE:INT;COLUMN:size" json:"size"`
+	Outcome       string `gorm:"NULL;TYPE:VARCHAR(50);COLUMN:outcome" json:"outcome"`
+	Accepted      int    `gorm:"NULL;TYPE:INT;COLUMN:accepted" json:"accepted"`
+	Rejected      int    `gorm:"NULL;TYPE:INT;COLUMN:rejected" json:"rejected"`
+	Dialect       string `gorm:"NULL;TYPE:TEXT;COLUMN:dialect" json:"dialect"`
+	Compression   string `gorm:"NULL;TYPE:VARCHAR(50);COLUMN:compression" json:"compression"`
+	RejectsReport string `gorm:"NULL;TYPE:TEXT;COLUMN:rejectsreport" json:"rejectsreport"`
+	Checksum      string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:checksum" json:"checksum"`
+	VersionID     string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:versionid" json:"versionid"`
+	Sequencer     string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:sequencer" json:"sequencer"`
+	RecordedAt    string `gorm:"NULL;TYPE:VARCHAR(200);COLUMN:recordedat" json:"recordedat"`
 }
 
 // TableName definition name for table .
@@ -0,0 +1,15 @@
+package entity
+
+// RejectedRow represents the entity.
+type RejectedRow struct {
+	TrackID string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:trackid" json:"trackid"`
+	Sheet   string `gorm:"primaryKey;TYPE:VARCHAR(200);COLUMN:sheet" json:"sheet"`
+	Line    int    `gorm:"primaryKey;autoIncrement:false;TYPE:INT;COLUMN:line" json:"line"`
+	Raw     string `gorm:"NULL;TYPE:TEXT;COLUMN:raw" json:"raw"`
+	Reason  string `gorm:"NULL;TYPE:TEXT;COLUMN:reason" json:"reason"`
+}
+
+// TableName definition name for table .
+func (RejectedRow) TableName() string {
+	return "rejected_rows"
+}
@@ -6,4 +6,6 @@ type FileData struct {
 	Message string `json:"message"`
 	Owner   string `json:"owner"`
 	Date    string `json:"date"`
+	// Extra are the columns of the file that aren't mapped to a field, by header name.
+	Extra map[string]string `json:"extra,omitempty"`
 }
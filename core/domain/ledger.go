@@ -0,0 +1,28 @@
+package domain
+
+// Statuses of an object in the ledger.
+const (
+	// ClaimPending objects are being ingested by the record of their track ID.
+	ClaimPending = "pending"
+	// ClaimIngested objects were ingested by the record of their track ID.
+	ClaimIngested = "ingested"
+)
+
+// ProcessedObject represents an S3 object claimed or already ingested.
+type ProcessedObject struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	VersionID string `json:"versionid"`
+	// Fingerprint is the ETag of the object, or the sequencer of its notification when the ETag is unknown.
+	Fingerprint string `json:"fingerprint"`
+	TrackID     string `json:"trackid"`
+	Status      string `json:"status"`
+}
+
+// ObjectSequencer represents the sequencer of the last event of an S3 object that was ingested.
+type ObjectSequencer struct {
+	Bucket    string `json:"bucket"`
+	Key       string `json:"key"`
+	Sequencer string `json:"sequencer"`
+	TrackID   string `json:"trackid"`
+}
@@ -2,9 +2,25 @@ package domain
 
 // MetaData represents the dto.
 type MetaData struct {
-	TrackID  string `json:"trackid"`
-	Bucket   string `json:"bucket"`
-	FileName string `json:"filename"`
-	Key      string `json:"key"`
-	Size     int64  `json:"size"`
+	TrackID string `json:"trackid"`
+	// ParentTrackID is the track ID of the archive a file was extracted from.
+	ParentTrackID string `json:"parenttrackid,omitempty"`
+	Bucket        string `json:"bucket"`
+	FileName      string `json:"filename"`
+	Key           string `json:"key"`
+	Size          int64  `json:"size"`
+	Outcome       string `json:"outcome"`
+	Accepted      int    `json:"accepted"`
+	Rejected      int    `json:"rejected"`
+	Dialect       string `json:"dialect,omitempty"`
+	Compression   string `json:"compression,omitempty"`
+	// RejectsReport is the S3 location of the report of the rejected rows, empty when no row was rejected.
+	RejectsReport string `json:"rejectsreport,omitempty"`
+	// Checksum is the checksum the object was verified against, prefixed by its algorithm like sha256:<value>.
+	Checksum string `json:"checksum,omitempty"`
+	// VersionID and Sequencer identify the version of the object and its event notification.
+	VersionID string `json:"versionid,omitempty"`
+	Sequencer string `json:"sequencer,omitempty"`
+	// RecordedAt is when the outcome was recorded, in UTC.
+	RecordedAt string `json:"recordedat,omitempty"`
 }
@@ -0,0 +1,10 @@
+package domain
+
+// RejectedRow represents the dto.
+type RejectedRow struct {
+	TrackID string `json:"trackid"`
+	Sheet   string `json:"sheet,omitempty"`
+	Line    int    `json:"line"`
+	Raw     string `json:"raw"`
+	Reason  string `json:"reason"`
+}
@@ -1,6 +1,10 @@
 package domain
 
-import "go.uber.org/zap"
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
 
 // Event represents a process.
 type Event struct {
@@ -17,7 +21,7 @@ type Event struct {
 
 // Source represents a source of filedata.
 type Source interface {
-	Consume() <-chan *Event
-	Processed(e *Event) error
-	Close() error
+	Consume(ctx context.Context) <-chan *Event
+	Processed(ctx context.Context, e *Event) error
+	Close(ctx context.Context) error
 }
@@ -0,0 +1,27 @@
+package rejected
+
+import (
+	"service-worker-sqs-s3-postgres/core/domain"
+	repository "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/rejected"
+)
+
+type IRejectedCaseUses interface {
+	GetByTrackID(trackID string) ([]*domain.RejectedRow, error)
+}
+
+// RejectedCaseUses encapsulates all the data necessary for the implementation of the RejectedRepository.
+type RejectedCaseUses struct {
+	rejectedRepository repository.IRejectedRepository
+}
+
+// NewRejectedUseCases instance the repository usecases.
+func NewRejectedUseCases(rr repository.IRejectedRepository) *RejectedCaseUses {
+	return &RejectedCaseUses{
+		rejectedRepository: rr,
+	}
+}
+
+// GetByTrackID return the rejected rows of a file by track ID.
+func (rr *RejectedCaseUses) GetByTrackID(trackID string) ([]*domain.RejectedRow, error) {
+	return rr.rejectedRepository.GetByTrackID(trackID)
+}
@@ -0,0 +1,36 @@
+package downloader
+
+import (
+	"fmt"
+	"service-worker-sqs-s3-postgres/dataproviders/utils"
+	"syscall"
+)
+
+// reserve checks that a local file of size bytes fits the spool limit and leaves the minimum free disk space.
+// A size of zero only checks the free disk space.
+func (d *S3Downloader) reserve(size int64) error {
+	if d.opts.MaxSpoolBytes > 0 && size > d.opts.MaxSpoolBytes {
+		return fmt.Errorf("%w: %d bytes, limit is %d", ErrTooLarge, size, d.opts.MaxSpoolBytes)
+	}
+	if d.opts.MinFreeBytes <= 0 {
+		return nil
+	}
+
+	free, err := freeSpace(utils.TmpPath)
+	if err != nil {
+		return err
+	}
+	if free-size < d.opts.MinFreeBytes {
+		return fmt.Errorf("%w: %d bytes free, %d needed", ErrLowDisk, free, size+d.opts.MinFreeBytes)
+	}
+	return nil
+}
+
+// freeSpace returns the bytes available in the file system of dir.
+func freeSpace(dir string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	s3client "service-worker-sqs-s3-postgres/dataproviders/awss3"
+	"strings"
+)
+
+// ErrIntegrity means the bytes read don't match the object held by S3. Reading the object again may succeed.
+var ErrIntegrity = errors.New("object doesn't match S3")
+
+// Verifier hashes the bytes of an object as they are read, to check them against the size and checksum S3 holds.
+type Verifier struct {
+	size      int64
+	read      int64
+	algorithm string
+	want      string
+	hash      hash.Hash
+	encode    func([]byte) string
+}
+
+// NewVerifier returns a verifier of an object of size bytes, zero to use the size of info. The checksum is, in
+// order of preference, the SHA-256 or CRC32C additional checksum of the object or its ETag when it's the MD5 of
+// the bytes, which isn't the case for multipart uploads and encrypted objects.
+func NewVerifier(size int64, info *s3client.ObjectInfo) *Verifier {
+	if size <= 0 {
+		size = info.Size
+	}
+	v := &Verifier{size: size}
+
+	etag := strings.Trim(info.ETag, `"`)
+	switch {
+	case isWholeChecksum(info.ChecksumSHA256):
+		v.algorithm, v.want, v.hash, v.encode = "sha256", info.ChecksumSHA256, sha256.New(), base64.StdEncoding.EncodeToString
+	case isWholeChecksum(info.ChecksumCRC32C):
+		v.algorithm, v.want, v.hash, v.encode = "crc32c", info.ChecksumCRC32C, crc32.New(crc32.MakeTable(crc32.Castagnoli)), base64.StdEncoding.EncodeToString
+	case !info.Encrypted && len(etag) == md5.Size*2 && !strings.Contains(etag, "-"):
+		v.algorithm, v.want, v.hash, v.encode = "md5", strings.ToLower(etag), md5.New(), hex.EncodeToString
+	}
+	return v
+}
+
+// Write hashes the next bytes of the object.
+func (v *Verifier) Write(p []byte) (int, error) {
+	v.read += int64(len(p))
+	if v.hash != nil {
+		v.hash.Write(p)
+	}
+	return len(p), nil
+}
+
+// Verify checks the bytes written so far are the whole object. It returns the verified checksum, prefixed by its
+// algorithm, or empty when S3 holds no checksum usable for the object.
+func (v *Verifier) Verify() (string, error) {
+	if v.size > 0 && v.read != v.size {
+		return "", fmt.Errorf("%w: read %d bytes, expected %d", ErrIntegrity, v.read, v.size)
+	}
+	if v.hash == nil {
+		return "", nil
+	}
+	if got := v.encode(v.hash.Sum(nil)); got != v.want {
+		return "", fmt.Errorf("%w: %s is %s, expected %s", ErrIntegrity, v.algorithm, got, v.want)
+	}
+	return v.algorithm + ":" + v.want, nil
+}
+
+// verifyingReader checks an object against S3 once its body is read to the end.
+type verifyingReader struct {
+	body     io.ReadCloser
+	verifier *Verifier
+}
+
+func (r verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	_, _ = r.verifier.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		if _, verr := r.verifier.Verify(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
+func (r verifyingReader) Close() error {
+	return r.body.Close()
+}
+
+// isWholeChecksum reports whether an additional checksum covers the whole object rather than its parts.
+func isWholeChecksum(checksum string) bool {
+	return checksum != "" && !strings.Contains(checksum, "-")
+}
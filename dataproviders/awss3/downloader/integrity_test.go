@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	s3client "service-worker-sqs-s3-postgres/dataproviders/awss3"
+	"strings"
+	"testing"
+)
+
+func TestVerifierVerify(t *testing.T) {
+	const contents = "id,message,owner\n1,hola,ventas\n"
+	md5Sum := md5.Sum([]byte(contents))
+	sha256Sum := sha256.Sum256([]byte(contents))
+	crc32c := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc32c, crc32.Checksum([]byte(contents), crc32.MakeTable(crc32.Castagnoli)))
+
+	etag := `"` + hex.EncodeToString(md5Sum[:]) + `"`
+	sha256B64 := base64.StdEncoding.EncodeToString(sha256Sum[:])
+	crc32cB64 := base64.StdEncoding.EncodeToString(crc32c)
+	size := int64(len(contents))
+
+	tests := []struct {
+		name     string
+		size     int64
+		info     s3client.ObjectInfo
+		contents string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "etag md5",
+			info:     s3client.ObjectInfo{Size: size, ETag: strings.ToUpper(etag)},
+			contents: contents,
+			want:     "md5:" + hex.EncodeToString(md5Sum[:]),
+		},
+		{
+			name:     "sha256 preferred over crc32c and etag",
+			info:     s3client.ObjectInfo{Size: size, ETag: etag, ChecksumSHA256: sha256B64, ChecksumCRC32C: crc32cB64},
+			contents: contents,
+			want:     "sha256:" + sha256B64,
+		},
+		{
+			name:     "crc32c preferred over etag",
+			info:     s3client.ObjectInfo{Size: size, ETag: etag, ChecksumCRC32C: crc32cB64},
+			contents: contents,
+			want:     "crc32c:" + crc32cB64,
+		},
+		{
+			name:     "checksum of the parts falls back to the etag",
+			info:     s3client.ObjectInfo{Size: size, ETag: etag, ChecksumSHA256: sha256B64 + "-2"},
+			contents: contents,
+			want:     "md5:" + hex.EncodeToString(md5Sum[:]),
+		},
+		{
+			name:     "multipart etag",
+			info:     s3client.ObjectInfo{Size: size, ETag: `"` + hex.EncodeToString(md5Sum[:]) + `-3"`},
+			contents: contents,
+		},
+		{
+			name:     "etag of an encrypted object",
+			info:     s3client.ObjectInfo{Size: size, ETag: etag, Encrypted: true},
+			contents: "other contents of the same size",
+		},
+		{
+			name:     "size of the range read",
+			size:     4,
+			info:     s3client.ObjectInfo{Size: size},
+			contents: "id,m",
+		},
+		{
+			name:     "bytes differ",
+			info:     s3client.ObjectInfo{Size: size, ETag: etag},
+			contents: strings.Replace(contents, "hola", "chau", 1),
+			wantErr:  ErrIntegrity,
+		},
+		{
+			name:     "sha256 differs",
+			info:     s3client.ObjectInfo{Size: size, ChecksumSHA256: sha256B64},
+			contents: strings.Replace(contents, "1", "2", 1),
+			wantErr:  ErrIntegrity,
+		},
+		{
+			name:     "truncated",
+			info:     s3client.ObjectInfo{Size: size},
+			contents: contents[:10],
+			wantErr:  ErrIntegrity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(tt.size, &tt.info)
+			// write in several chunks, the way the body is read.
+			for _, chunk := range strings.SplitAfter(tt.contents, "\n") {
+				_, _ = v.Write([]byte(chunk))
+			}
+			got, err := v.Verify()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	const contents = "id,message,owner\n1,hola,ventas\n"
+	sum := md5.Sum([]byte(contents))
+	info := &s3client.ObjectInfo{Size: int64(len(contents)), ETag: hex.EncodeToString(sum[:])}
+
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  error
+	}{
+		{name: "whole object", contents: contents},
+		{name: "corrupted object", contents: strings.ToUpper(contents), wantErr: ErrIntegrity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := verifyingReader{body: io.NopCloser(strings.NewReader(tt.contents)), verifier: NewVerifier(0, info)}
+			got, err := io.ReadAll(r)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.contents {
+				t.Errorf("ReadAll() = %q, want %q", got, tt.contents)
+			}
+		})
+	}
+}
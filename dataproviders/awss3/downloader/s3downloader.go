@@ -1,50 +1,263 @@
 package downloader
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path"
 	s3client "service-worker-sqs-s3-postgres/dataproviders/awss3"
 	"service-worker-sqs-s3-postgres/dataproviders/utils"
 
-	"github.com/labstack/gommon/log"
 	"github.com/spf13/afero"
 	"go.uber.org/zap"
 )
 
+// Mode is how the objects are read from S3.
+type Mode string
+
+const (
+	// Stream hands the body of the object straight to the reader. Only formats that need random access are spooled.
+	Stream Mode = "stream"
+	// File downloads the whole object to a local file before reading it.
+	File Mode = "file"
+)
+
+var (
+	// ErrTooLarge means a file is larger than the spool limit.
+	ErrTooLarge = errors.New("file exceeds the spool limit")
+	// ErrLowDisk means the local disk hasn't room for a file right now.
+	ErrLowDisk = errors.New("not enough free disk space")
+)
+
+// ParseMode returns the mode with the given name.
+func ParseMode(name string) (Mode, error) {
+	switch mode := Mode(name); mode {
+	case Stream, File:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown download mode %q", name)
+}
+
+// Options represents how the objects are read and the limits of the local files.
+type Options struct {
+	Mode Mode
+	// MaxSpoolBytes caps the size of a local file. Zero means no limit.
+	MaxSpoolBytes int64
+	// MinFreeBytes is the free disk space left after writing a local file. Zero disables the check.
+	MinFreeBytes int64
+}
+
 // S3Downloader represents a S3 handler.
 type S3Downloader struct {
-	fs  afero.Fs
-	s3  *s3client.ClientS3
-	log *zap.SugaredLogger
+	fs   afero.Fs
+	s3   *s3client.ClientS3
+	log  *zap.SugaredLogger
+	opts Options
 }
 
-// NewDownloader instances a S3Downloader.
-func NewDownloader(s3 *s3client.ClientS3, log *zap.SugaredLogger) (*S3Downloader, error) {
+// NewDownloader instances a S3Downloader. Files left in the local folder by a previous run are removed.
+func NewDownloader(s3 *s3client.ClientS3, log *zap.SugaredLogger, opts Options) (*S3Downloader, error) {
+	if opts.Mode == "" {
+		opts.Mode = Stream
+	}
+
 	fs := afero.NewOsFs()
 	if err := fs.MkdirAll(utils.TmpPath, os.ModePerm); err != nil {
 		return nil, err
 	}
-	return &S3Downloader{fs: fs, s3: s3, log: log}, nil
+	d := &S3Downloader{fs: fs, s3: s3, log: log, opts: opts}
+	if err := d.removeOrphans(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Mode returns how the objects are read.
+func (d *S3Downloader) Mode() Mode {
+	return d.opts.Mode
 }
 
-// Download downloads a file from S3 bucket.
-func (d *S3Downloader) Download(bucket, key string) (string, error) {
+// Download downloads a version of a file from S3 bucket, the latest one when version is empty, and checks it with
+// verifier. The local file is removed when the download fails or doesn't match S3.
+func (d *S3Downloader) Download(ctx context.Context, bucket, key, version string, verifier *Verifier) (string, error) {
+	if err := d.reserve(verifier.size); err != nil {
+		d.log.Errorf("s3downloader: error downloading file %s. %v", key, err)
+		return "", err
+	}
+
 	localPath := utils.CreateLocalFileName(key)
 	dst, err := d.fs.Create(localPath)
 	if err != nil {
-		log.Errorf("s3downloader: error creating dst file %s: %v", localPath, err)
+		d.log.Errorf("s3downloader: error creating dst file %s: %v", localPath, err)
 		return "", err
 	}
 	defer utils.Close(dst, d.log)
 
-	err = d.s3.DownloadFile(bucket, key, dst)
+	err = d.s3.DownloadFile(ctx, bucket, key, version, dst)
+	if err == nil {
+		err = d.verify(dst, verifier)
+	}
 	if err != nil {
 		d.log.Errorf("s3downloader: error downloading file %s. %v", key, err)
+		d.remove(localPath)
+		return "", err
+	}
+	return localPath, nil
+}
+
+// Stream returns the body of a version of a file of S3 bucket, the latest one when version is empty, to be closed
+// by the caller. Reading the body to the end fails with ErrIntegrity when it doesn't match verifier.
+func (d *S3Downloader) Stream(ctx context.Context, bucket, key, version string, verifier *Verifier) (io.ReadCloser, error) {
+	body, err := d.s3.GetObject(ctx, bucket, key, version)
+	if err != nil {
+		d.log.Errorf("s3downloader: error streaming file %s. %v", key, err)
+		return nil, err
+	}
+	return verifyingReader{body: body, verifier: verifier}, nil
+}
+
+// Head returns the attributes of the version of the object to download.
+func (d *S3Downloader) Head(ctx context.Context, bucket, key, version string) (*s3client.ObjectInfo, error) {
+	info, err := d.s3.Head(ctx, bucket, key, version)
+	if err != nil {
+		d.log.Errorf("s3downloader: error reading attributes of file %s. %v", key, err)
+		return nil, err
+	}
+	return info, nil
+}
+
+// Tags returns the tags of the version of the object to download.
+func (d *S3Downloader) Tags(ctx context.Context, bucket, key, version string) (map[string]string, error) {
+	tags, err := d.s3.Tags(ctx, bucket, key, version)
+	if err != nil {
+		d.log.Errorf("s3downloader: error reading tags of file %s. %v", key, err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Spool writes the contents of r to a local file, for readers that need random access to a stream.
+// It fails with ErrTooLarge once the contents exceed the spool limit.
+func (d *S3Downloader) Spool(r io.Reader, name string) (string, error) {
+	if err := d.reserve(0); err != nil {
+		d.log.Errorf("s3downloader: error spooling file %s. %v", name, err)
+		return "", err
+	}
+	if d.opts.MaxSpoolBytes > 0 {
+		r = &limitedReader{r: r, n: d.opts.MaxSpoolBytes}
+	}
+
+	localPath := utils.CreateLocalFileName(name)
+	dst, err := d.fs.Create(localPath)
+	if err != nil {
+		d.log.Errorf("s3downloader: error creating spool file %s: %v", localPath, err)
+		return "", err
+	}
+	defer utils.Close(dst, d.log)
+
+	if _, err = io.Copy(dst, r); err != nil {
+		d.log.Errorf("s3downloader: error spooling file %s. %v", name, err)
+		d.remove(localPath)
 		return "", err
 	}
 	return localPath, nil
 }
 
+// Create creates a local file named after name to be uploaded later.
+func (d *S3Downloader) Create(name string) (string, afero.File, error) {
+	localPath := utils.CreateLocalFileName(name)
+	file, err := d.fs.Create(localPath)
+	if err != nil {
+		d.log.Errorf("s3downloader: error creating file %s: %v", localPath, err)
+		return "", nil, err
+	}
+	return localPath, file, nil
+}
+
+// Upload uploads a local file to S3 bucket.
+func (d *S3Downloader) Upload(ctx context.Context, bucket, key, contentType, file string) error {
+	src, err := d.fs.Open(file)
+	if err != nil {
+		d.log.Errorf("s3downloader: error opening file %s: %v", file, err)
+		return err
+	}
+	defer utils.Close(src, d.log)
+
+	if err = d.s3.UploadFile(ctx, bucket, key, contentType, src); err != nil {
+		d.log.Errorf("s3downloader: error uploading file %s. %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteObject deletes an object uploaded to S3 bucket.
+func (d *S3Downloader) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := d.s3.DeleteObject(ctx, bucket, key); err != nil {
+		d.log.Errorf("s3downloader: error deleting object %s. %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// Open opens a downloaded file for reading.
+func (d *S3Downloader) Open(file string) (afero.File, error) {
+	return d.fs.Open(file)
+}
+
 // Delete local file.
 func (d *S3Downloader) Delete(file string) error {
 	return d.fs.Remove(file)
 }
+
+// verify hashes a downloaded file with verifier and checks it matches S3.
+func (d *S3Downloader) verify(file afero.File, verifier *Verifier) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(verifier, file); err != nil {
+		return err
+	}
+	_, err := verifier.Verify()
+	return err
+}
+
+// remove deletes a local file that failed to be written.
+func (d *S3Downloader) remove(file string) {
+	if err := d.fs.Remove(file); err != nil && !os.IsNotExist(err) {
+		d.log.Errorf("s3downloader: error deleting file %s: %v", file, err)
+	}
+}
+
+// removeOrphans deletes the local files of a previous run, which was stopped before cleaning up after itself.
+func (d *S3Downloader) removeOrphans() error {
+	entries, err := afero.ReadDir(d.fs, utils.TmpPath)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err = d.fs.RemoveAll(path.Join(utils.TmpPath, entry.Name())); err != nil {
+			return err
+		}
+	}
+	if len(entries) > 0 {
+		d.log.Infof("s3downloader: removed %d orphaned file(s) from %s", len(entries), utils.TmpPath)
+	}
+	return nil
+}
+
+// limitedReader fails with ErrTooLarge once more than n bytes are read.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
@@ -1,11 +1,16 @@
 package awss3
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net/http"
 	"service-worker-sqs-s3-postgres/dataproviders/utils"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
@@ -16,36 +21,203 @@ import (
 type ClientS3 struct {
 	api        s3iface.S3API
 	downloader *s3manager.Downloader
+	uploader   *s3manager.Uploader
 	bucket     string
 }
 
+// ObjectInfo represents the attributes of an S3 object.
+type ObjectInfo struct {
+	ContentType     string
+	ContentEncoding string
+	ETag            string
+	Size            int64
+	// ChecksumSHA256 and ChecksumCRC32C are the base64 additional checksums of the object, when it has one.
+	ChecksumSHA256 string
+	ChecksumCRC32C string
+	// Encrypted tells whether the object is encrypted with KMS or a customer key, whose ETag isn't the MD5 of its bytes.
+	Encrypted bool
+	// Metadata is the user metadata of the object, with lowercase keys and without the x-amz-meta- prefix.
+	Metadata map[string]string
+}
+
 // NewS3Client instantiates a new Client.
 func NewS3Client(sess *session.Session, bucket string, cfgs ...*aws.Config) (*ClientS3, error) {
 	api := s3.New(sess, cfgs...)
 	return &ClientS3{
 		api:        api,
 		downloader: s3manager.NewDownloaderWithClient(api),
+		uploader:   s3manager.NewUploaderWithClient(api),
 		bucket:     bucket,
 	}, nil
 }
 
-// DownloadFile download a file from S3 bucket.
-func (c *ClientS3) DownloadFile(bucket, key string, file io.WriterAt) error {
+// DownloadFile download a file from S3 bucket, in the version given or else the latest one.
+func (c *ClientS3) DownloadFile(ctx context.Context, bucket, key, version string, file io.WriterAt) error {
 	if len(bucket) == 0 {
 		bucket = c.bucket
 	}
 
 	params := &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: versionID(version),
 	}
 
 	err := utils.Do(5, 3*time.Second, func() (bool, error) {
-		_, err := c.downloader.Download(file, params)
-		return true, err
+		_, err := c.downloader.DownloadWithContext(ctx, file, params)
+		return retryable(ctx, err), err
 	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// GetObject returns the body of an object of a S3 bucket, in the version given or else the latest one, to be closed
+// by the caller.
+func (c *ClientS3) GetObject(ctx context.Context, bucket, key, version string) (io.ReadCloser, error) {
+	if len(bucket) == 0 {
+		bucket = c.bucket
+	}
+
+	params := &s3.GetObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: versionID(version),
+	}
+
+	var out *s3.GetObjectOutput
+	err := utils.Do(5, 3*time.Second, func() (bool, error) {
+		var err error
+		out, err = c.api.GetObjectWithContext(ctx, params)
+		return retryable(ctx, err), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+// UploadFile uploads a file to S3 bucket. The body is read once, so the upload isn't retried.
+func (c *ClientS3) UploadFile(ctx context.Context, bucket, key, contentType string, body io.Reader) error {
+	if len(bucket) == 0 {
+		bucket = c.bucket
+	}
+
+	_, err := c.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	return err
+}
+
+// DeleteObject deletes an object of a S3 bucket.
+func (c *ClientS3) DeleteObject(ctx context.Context, bucket, key string) error {
+	if len(bucket) == 0 {
+		bucket = c.bucket
+	}
+
+	params := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+
+	return utils.Do(5, 3*time.Second, func() (bool, error) {
+		_, err := c.api.DeleteObjectWithContext(ctx, params)
+		return retryable(ctx, err), err
+	})
+}
+
+// Head returns the attributes of an object of a S3 bucket, in the version given or else the latest one.
+func (c *ClientS3) Head(ctx context.Context, bucket, key, version string) (*ObjectInfo, error) {
+	if len(bucket) == 0 {
+		bucket = c.bucket
+	}
+
+	params := &s3.HeadObjectInput{
+		Bucket:       aws.String(bucket),
+		Key:          aws.String(key),
+		VersionId:    versionID(version),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	}
+
+	var out *s3.HeadObjectOutput
+	err := utils.Do(5, 3*time.Second, func() (bool, error) {
+		var err error
+		out, err = c.api.HeadObjectWithContext(ctx, params)
+		return retryable(ctx, err), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(out.Metadata))
+	for k, v := range out.Metadata {
+		metadata[strings.ToLower(k)] = aws.StringValue(v)
+	}
+
+	return &ObjectInfo{
+		ContentType:     aws.StringValue(out.ContentType),
+		ContentEncoding: aws.StringValue(out.ContentEncoding),
+		ETag:            aws.StringValue(out.ETag),
+		Size:            aws.Int64Value(out.ContentLength),
+		ChecksumSHA256:  aws.StringValue(out.ChecksumSHA256),
+		ChecksumCRC32C:  aws.StringValue(out.ChecksumCRC32C),
+		Encrypted:       strings.HasPrefix(aws.StringValue(out.ServerSideEncryption), "aws:kms") || out.SSECustomerAlgorithm != nil,
+		Metadata:        metadata,
+	}, nil
+}
+
+// Tags returns the tags of an object of a S3 bucket, in the version given or else the latest one.
+func (c *ClientS3) Tags(ctx context.Context, bucket, key, version string) (map[string]string, error) {
+	if len(bucket) == 0 {
+		bucket = c.bucket
+	}
+
+	params := &s3.GetObjectTaggingInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: versionID(version),
+	}
+
+	var out *s3.GetObjectTaggingOutput
+	err := utils.Do(5, 3*time.Second, func() (bool, error) {
+		var err error
+		out, err = c.api.GetObjectTaggingWithContext(ctx, params)
+		return retryable(ctx, err), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(out.TagSet))
+	for _, tag := range out.TagSet {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags, nil
+}
+
+// versionID returns the version of an object to request, nil for the latest one. Objects of buckets without
+// versioning have no version ID, or the "null" one, which is also the latest.
+func versionID(version string) *string {
+	if version == "" || version == "null" {
+		return nil
+	}
+	return aws.String(version)
+}
+
+// retryable reports whether a failed request may succeed if retried. Client errors, like a missing object or a
+// denied access, fail the same way every time, except for timeouts and throttling.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		status := reqErr.StatusCode()
+		return status < 400 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -1,6 +1,11 @@
 package awssqs
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
@@ -13,6 +18,8 @@ type ClientSQS struct {
 	url               string
 	maxMessages       int64
 	visibilityTimeout int64
+	fifo              bool
+	attemptID         string
 }
 
 // NewSQSClient instances of a Client to connect SQS with session as parameter.
@@ -22,11 +29,13 @@ func NewSQSClient(sess *session.Session, url string, maxMessages, visibilityTime
 		url:               url,
 		maxMessages:       int64(maxMessages),
 		visibilityTimeout: int64(visibilityTimeout),
+		fifo:              strings.HasSuffix(url, ".fifo"),
 	}, nil
 }
 
-// GetMessages retrieves messages from SQS.
-func (s *ClientSQS) GetMessages() ([]*sqs.Message, error) {
+// GetMessages retrieves messages from SQS. On FIFO queues a failed receive is retried with the same
+// ReceiveRequestAttemptId, so it isn't safe to call it concurrently on the same client.
+func (s *ClientSQS) GetMessages(ctx context.Context) ([]*sqs.Message, error) {
 	params := &sqs.ReceiveMessageInput{
 		QueueUrl:            aws.String(s.url),
 		MaxNumberOfMessages: aws.Int64(s.maxMessages),
@@ -40,21 +49,85 @@ func (s *ClientSQS) GetMessages() ([]*sqs.Message, error) {
 		VisibilityTimeout: aws.Int64(s.visibilityTimeout),
 	}
 
-	res, err := s.api.ReceiveMessage(params)
+	if s.fifo {
+		if s.attemptID == "" {
+			s.attemptID = newAttemptID()
+		}
+		params.ReceiveRequestAttemptId = aws.String(s.attemptID)
+	}
+
+	res, err := s.api.ReceiveMessageWithContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	s.attemptID = ""
 
 	return res.Messages, nil
 }
 
 // DeleteMessage deletes messages from SQS.
-func (s *ClientSQS) DeleteMessage(msg *sqs.Message) error {
+func (s *ClientSQS) DeleteMessage(ctx context.Context, msg *sqs.Message) error {
 	params := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(s.url),
 		ReceiptHandle: msg.ReceiptHandle,
 	}
-	_, err := s.api.DeleteMessage(params)
+	_, err := s.api.DeleteMessageWithContext(ctx, params)
+
+	return err
+}
+
+// SendMessage sends a message with the given attributes to SQS. The group and deduplication IDs are only
+// used by FIFO queues.
+func (s *ClientSQS) SendMessage(ctx context.Context, body string, attributes map[string]*sqs.MessageAttributeValue, groupID, deduplicationID string) error {
+	params := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.url),
+		MessageBody:       aws.String(body),
+		MessageAttributes: attributes,
+	}
+	if s.fifo {
+		params.MessageGroupId = aws.String(groupID)
+		params.MessageDeduplicationId = aws.String(deduplicationID)
+	}
+	_, err := s.api.SendMessageWithContext(ctx, params)
+
+	return err
+}
+
+// URL returns the url of the queue.
+func (s *ClientSQS) URL() string {
+	return s.url
+}
+
+// ChangeMessageVisibility resets the visibility timeout of a message, in seconds.
+func (s *ClientSQS) ChangeMessageVisibility(ctx context.Context, msg *sqs.Message, timeout int64) error {
+	params := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(s.url),
+		ReceiptHandle:     msg.ReceiptHandle,
+		VisibilityTimeout: aws.Int64(timeout),
+	}
+	_, err := s.api.ChangeMessageVisibilityWithContext(ctx, params)
 
 	return err
 }
+
+// VisibilityTimeout returns the visibility timeout requested for received messages, in seconds.
+func (s *ClientSQS) VisibilityTimeout() int64 {
+	return s.visibilityTimeout
+}
+
+// IsFIFO reports whether the queue is a FIFO queue.
+func (s *ClientSQS) IsFIFO() bool {
+	return s.fifo
+}
+
+// newAttemptID returns a random ReceiveRequestAttemptId.
+func newAttemptID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// MaxMessages returns the maximum number of messages retrieved per receive.
+func (s *ClientSQS) MaxMessages() int64 {
+	return s.maxMessages
+}
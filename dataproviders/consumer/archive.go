@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"service-worker-sqs-s3-postgres/dataproviders/utils"
+	"strings"
+)
+
+var errNoEntries = errors.New("archive has no readable entries")
+
+// readArchive reads every entry of a zip archive whose format is known. Each entry is recorded as its own metadata
+// under the track ID of the archive, and the report of the archive adds up the rows of all its entries. The entries
+// are written in the transaction of the archive, so an entry that fails rolls back the ones read before it.
+func (s *SQSSource) readArchive(ctx context.Context, s3Event *s3Event, at io.ReaderAt, size int64) (*record.Report, error) {
+	logger := s3Event.log
+	report := record.NewReport(s.rejects)
+
+	archive, err := zip.NewReader(at, size)
+	if err != nil {
+		return report, record.Unreadable(err)
+	}
+
+	entries := 0
+	for _, f := range archive.File {
+		if !isEntryReadable(f, s.parsers) {
+			logger.Debugf("Step 4 - Skipping entry %s of archive", f.Name)
+			continue
+		}
+
+		entry := s3Event.entry(entries, f)
+		entries++
+
+		entryReport, err := s.readEntry(ctx, entry, f)
+		report.Accepted += entryReport.Accepted
+		report.Rejected += entryReport.Rejected
+		if err != nil {
+			return report, &entryError{entry: entry, report: entryReport, err: err}
+		}
+
+		metadata := entry.metadata(f.Name, domain.Ingested)
+		metadata.Accepted, metadata.Rejected = entryReport.Accepted, entryReport.Rejected
+		if err = s.rMetadata.Insert(ctx, metadata); err != nil {
+			return report, fmt.Errorf("error inserting message in MetaData: %w", err)
+		}
+		entry.log.Infof("Step 4 - Entry saved in postgres: %d row(s) accepted, %d rejected", entryReport.Accepted, entryReport.Rejected)
+	}
+
+	if entries == 0 {
+		return report, record.Unreadable(errNoEntries)
+	}
+	return report, nil
+}
+
+// readEntry streams the rows of an entry of a zip archive.
+func (s *SQSSource) readEntry(ctx context.Context, entry *s3Event, f *zip.File) (*record.Report, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return record.NewReport(s.rejects), record.Unreadable(err)
+	}
+	defer utils.Close(rc, entry.log)
+
+	return s.readContents(ctx, entry, f.Name, rc, nil, 0)
+}
+
+// entryError is the failure of an entry of an archive, recorded once the transaction of the archive is rolled back.
+type entryError struct {
+	entry  *s3Event
+	report *record.Report
+	err    error
+}
+
+func (e *entryError) Error() string {
+	return fmt.Sprintf("entry %s: %v", e.entry.entryName, e.err)
+}
+
+func (e *entryError) Unwrap() error {
+	return e.err
+}
+
+// entry returns the record of the i-th readable entry of the archive of e.
+func (e *s3Event) entry(i int, f *zip.File) *s3Event {
+	entry := *e
+	entry.trackID = fmt.Sprintf("%s-%d", e.trackID, i)
+	entry.parentTrackID = e.trackID
+	entry.entryName = f.Name
+	entry.rejectsReport = ""
+	entry.fileSize = int64(f.UncompressedSize64)
+	entry.dialect = ""
+	entry.log = e.log.With("entry", f.Name)
+	return &entry
+}
+
+// isEntryReadable reports whether an entry of a zip archive has the extension of a registered parser. Directories,
+// hidden files and the resource forks added by macOS are skipped.
+func isEntryReadable(f *zip.File, parsers *parser.Registry) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), ".") {
+		return false
+	}
+	return parsers.Knows(f.Name)
+}
@@ -0,0 +1,89 @@
+package consumer
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// maxVisibilityTimeout is the longest visibility timeout accepted by SQS.
+const maxVisibilityTimeout = 12 * time.Hour
+
+// GiveUpPolicy defines what happens to a message once it has exhausted its retries.
+type GiveUpPolicy string
+
+const (
+	// GiveUpDeadLetter sends the message to the dead-letter queue.
+	GiveUpDeadLetter GiveUpPolicy = "deadletter"
+	// GiveUpDelete deletes the message from the queue.
+	GiveUpDelete GiveUpPolicy = "delete"
+	// GiveUpKeep leaves the message in the queue for its redrive policy.
+	GiveUpKeep GiveUpPolicy = "keep"
+)
+
+// ParseGiveUpPolicy validates the name of a give up policy.
+func ParseGiveUpPolicy(name string) (GiveUpPolicy, error) {
+	switch policy := GiveUpPolicy(name); policy {
+	case GiveUpDeadLetter, GiveUpDelete, GiveUpKeep:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid give up policy %q", name)
+	}
+}
+
+// Backoff computes the redelivery delay of messages that failed with a retryable error.
+type Backoff struct {
+	// Base is the delay after the first attempt; it doubles on every attempt.
+	Base time.Duration
+	// Max caps the delay.
+	Max time.Duration
+	// MaxAttempts is the number of receives after which the message is given up. Zero retries forever.
+	MaxAttempts int
+	// GiveUp is applied once MaxAttempts is reached.
+	GiveUp GiveUpPolicy
+}
+
+// Delay returns the delay before the next attempt, with jitter, after the given attempt.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if b.Base <= 0 || attempt < 1 {
+		return 0
+	}
+
+	limit := b.Max
+	if limit <= 0 || limit > maxVisibilityTimeout {
+		limit = maxVisibilityTimeout
+	}
+
+	delay := b.Base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Exhausted reports whether the given attempt is the last one allowed.
+func (b Backoff) Exhausted(attempt int) bool {
+	return b.MaxAttempts > 0 && attempt >= b.MaxAttempts
+}
+
+// receiveCount returns how many times SQS has delivered the message.
+func receiveCount(msg *sqs.Message) int {
+	val, ok := msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]
+	if !ok {
+		return 1
+	}
+	count, err := strconv.Atoi(aws.StringValue(val))
+	if err != nil || count < 1 {
+		return 1
+	}
+	return count
+}
@@ -0,0 +1,96 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		attempt int
+		// the delay is drawn from [min, max], half of the computed delay being jitter.
+		min, max time.Duration
+	}{
+		{
+			name:    "first attempt",
+			backoff: Backoff{Base: 10 * time.Second, Max: time.Hour},
+			attempt: 1,
+			min:     5 * time.Second,
+			max:     10 * time.Second,
+		},
+		{
+			name:    "doubles on every attempt",
+			backoff: Backoff{Base: 10 * time.Second, Max: time.Hour},
+			attempt: 4,
+			min:     40 * time.Second,
+			max:     80 * time.Second,
+		},
+		{
+			name:    "capped by max",
+			backoff: Backoff{Base: 10 * time.Second, Max: time.Minute},
+			attempt: 10,
+			min:     30 * time.Second,
+			max:     time.Minute,
+		},
+		{
+			name:    "capped by the sqs visibility timeout",
+			backoff: Backoff{Base: time.Hour, Max: 24 * time.Hour},
+			attempt: 10,
+			min:     maxVisibilityTimeout / 2,
+			max:     maxVisibilityTimeout,
+		},
+		{
+			name:    "no max uses the sqs visibility timeout",
+			backoff: Backoff{Base: time.Hour},
+			attempt: 100,
+			min:     maxVisibilityTimeout / 2,
+			max:     maxVisibilityTimeout,
+		},
+		{
+			name:    "no base",
+			backoff: Backoff{Max: time.Hour},
+			attempt: 3,
+		},
+		{
+			name:    "no attempt",
+			backoff: Backoff{Base: 10 * time.Second, Max: time.Hour},
+			attempt: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := tt.backoff.Delay(tt.attempt)
+				if got < tt.min || got > tt.max {
+					t.Fatalf("Delay(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffExhausted(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		attempt     int
+		want        bool
+	}{
+		{name: "before the last attempt", maxAttempts: 3, attempt: 2, want: false},
+		{name: "last attempt", maxAttempts: 3, attempt: 3, want: true},
+		{name: "after the last attempt", maxAttempts: 3, attempt: 5, want: true},
+		{name: "retries forever", maxAttempts: 0, attempt: 1000, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Backoff{MaxAttempts: tt.maxAttempts}
+			if got := b.Exhausted(tt.attempt); got != tt.want {
+				t.Errorf("Exhausted(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package compression
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is the compression of the contents of an object.
+type Codec string
+
+const (
+	None  Codec = ""
+	Gzip  Codec = "gzip"
+	Zstd  Codec = "zstd"
+	Bzip2 Codec = "bzip2"
+	// Zip is an archive of files rather than a stream, its entries are read with archive/zip.
+	Zip Codec = "zip"
+)
+
+// MagicLen is the number of leading bytes needed to recognise every codec.
+const MagicLen = 4
+
+var (
+	magics = []struct {
+		codec Codec
+		magic []byte
+	}{
+		{Gzip, []byte{0x1f, 0x8b}},
+		{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+		{Bzip2, []byte("BZh")},
+		{Zip, []byte("PK\x03\x04")},
+	}
+	byExtension = map[string]Codec{
+		".gz":   Gzip,
+		".gzip": Gzip,
+		".zst":  Zstd,
+		".zstd": Zstd,
+		".bz2":  Bzip2,
+		".zip":  Zip,
+	}
+	byEncoding = map[string]Codec{
+		"gzip":    Gzip,
+		"x-gzip":  Gzip,
+		"zstd":    Zstd,
+		"bzip2":   Bzip2,
+		"x-bzip2": Bzip2,
+	}
+)
+
+// Detect returns the codec of an object from its first bytes. The extension of name and the Content-Encoding are
+// only used when the object is too short to tell, since S3 clients may already have decoded the Content-Encoding.
+func Detect(head []byte, name, contentEncoding string) Codec {
+	if len(head) >= MagicLen {
+		for _, m := range magics {
+			if bytes.HasPrefix(head, m.magic) {
+				return m.codec
+			}
+		}
+		return None
+	}
+
+	if codec, ok := byExtension[strings.ToLower(path.Ext(name))]; ok {
+		return codec
+	}
+	return byEncoding[strings.ToLower(strings.TrimSpace(contentEncoding))]
+}
+
+// NewReader returns a reader decompressing r with a stream codec.
+func NewReader(codec Codec, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case None:
+		return io.NopCloser(r), nil
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	case Bzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("%s isn't a stream codec", codec)
+}
+
+// TrimExtension returns name without the extension of codec, so the format of the contents can be told by name.
+func TrimExtension(name string, codec Codec) string {
+	ext := path.Ext(name)
+	if c, ok := byExtension[strings.ToLower(ext)]; ok && c == codec {
+		return strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+package compression
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name            string
+		head            []byte
+		file            string
+		contentEncoding string
+		want            Codec
+	}{
+		{name: "gzip magic", head: []byte{0x1f, 0x8b, 0x08, 0x00}, file: "file.csv", want: Gzip},
+		{name: "zstd magic", head: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, file: "file.csv", want: Zstd},
+		{name: "bzip2 magic", head: []byte("BZh91AY"), file: "file.csv", want: Bzip2},
+		{name: "zip magic", head: []byte("PK\x03\x04\x14"), file: "file.csv", want: Zip},
+		{name: "plain contents", head: []byte("id,message"), file: "file.csv", want: None},
+		{
+			name:            "magic wins over the extension and the encoding",
+			head:            []byte{0x28, 0xb5, 0x2f, 0xfd},
+			file:            "file.csv.gz",
+			contentEncoding: "gzip",
+			want:            Zstd,
+		},
+		{
+			name:            "contents already decoded by the client",
+			head:            []byte("id,message"),
+			file:            "file.csv.gz",
+			contentEncoding: "gzip",
+			want:            None,
+		},
+		{name: "short object by extension", head: []byte{0x1f, 0x8b}, file: "dir/FILE.CSV.GZ", want: Gzip},
+		{name: "empty object by extension", file: "file.csv.zst", want: Zstd},
+		{name: "short object by encoding", head: []byte("a"), file: "file", contentEncoding: " X-Bzip2 ", want: Bzip2},
+		{name: "extension wins over the encoding", file: "file.zip", contentEncoding: "gzip", want: Zip},
+		{name: "short object unknown", head: []byte("a"), file: "file.csv", contentEncoding: "br", want: None},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.head, tt.file, tt.contentEncoding); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimExtension(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		codec Codec
+		want  string
+	}{
+		{name: "extension of the codec", file: "dir/file.csv.gz", codec: Gzip, want: "dir/file.csv"},
+		{name: "upper case extension", file: "file.xlsx.ZST", codec: Zstd, want: "file.xlsx"},
+		{name: "extension of another codec", file: "file.csv.gz", codec: Zstd, want: "file.csv.gz"},
+		{name: "no extension of a codec", file: "file.csv", codec: None, want: "file.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrimExtension(tt.file, tt.codec); got != tt.want {
+				t.Errorf("TrimExtension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,78 +1,227 @@
 package consumer
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/sqs"
-	"github.com/tidwall/gjson"
 	"go.uber.org/zap"
+	"io"
+	"path"
 	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/awss3"
 	"service-worker-sqs-s3-postgres/dataproviders/awss3/downloader"
-	"service-worker-sqs-s3-postgres/dataproviders/awssqs"
-	"service-worker-sqs-s3-postgres/dataproviders/consumer/csvreader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/compression"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/rejectreport"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/validation"
+	"service-worker-sqs-s3-postgres/dataproviders/postgres"
 	rfiledata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/filedata"
+	rledger "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/ledger"
 	rmetadata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/metadata"
+	rrejected "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/rejected"
+	"service-worker-sqs-s3-postgres/dataproviders/utils"
 	"sync"
+	"time"
 )
 
 // SQSSource event stream representation to SQS.
 type SQSSource struct {
-	sqs         *awssqs.ClientSQS
+	queues      []*queue
 	download    *downloader.S3Downloader
 	log         *zap.SugaredLogger
 	maxMessages int
-	closed      bool
-	rFiledata   rfiledata.IFileDataRepository
 	rMetadata   rmetadata.IMetaDataRepository
-	wg          sync.WaitGroup
+	rLedger     rledger.ILedgerRepository
+	rRejected   rrejected.IRejectedRepository
+	tx          postgres.ITransactor
+	rejects     record.Policy
+	parsers     *parser.Registry
+	rules       validation.Rules
+	reports     rejectreport.Options
+	chunkSize   int
+	// claimTimeout is how long the claim of an object lasts before another record can take it over.
+	claimTimeout time.Duration
+	backoff      Backoff
+	decoders     Decoders
+	wg           sync.WaitGroup
+
+	heartbeatInterval time.Duration
+	mu                sync.Mutex
+	done              chan struct{}
+	closeOnce         sync.Once
+	stopping          chan struct{}
+	stopOnce          sync.Once
+	stopPolling       context.CancelFunc
+	abortWork         context.CancelFunc
+	// workCtx bounds the processing of messages, it is done once Close gives up waiting for them.
+	workCtx context.Context
 }
 
 type s3Event struct {
-	bucket     string
-	key        string
-	fileSize   int64
-	sqsMessage *sqs.Message
+	trackID   string
+	index     int
+	bucket    string
+	key       string
+	fileSize  int64
+	versionID string
+	etag      string
+	sequencer string
+	// claimed tells whether the record holds the claim of its object in the ledger.
+	claimed bool
+	object  *awss3.ObjectInfo
+	// tags are the tags of the object, only read when a parser can be chosen by tag.
+	tags    map[string]string
+	dialect string
+	// checksum is the verified checksum of the object, prefixed by its algorithm.
+	checksum string
+	// compression is the codec the object was stored with.
+	compression string
+	// parentTrackID is the track ID of the archive an entry was extracted from, and entryName its name in the archive.
+	parentTrackID string
+	entryName     string
+	// rejectsReport is the location of the report of the rejected rows.
+	rejectsReport string
+	// reports are the rejected rows reports of the record, shared with the entries of its archive.
+	reports  *rejectsReports
+	filename string
+	message  *message
+	log      *zap.SugaredLogger
 }
 
+// DefaultClaimTimeout is how long the claim of an object lasts when no timeout is given.
+const DefaultClaimTimeout = time.Hour
+
 var (
 	errInvalidJSON        = errors.New("invalid json")
 	errNoRecordsFound     = errors.New("no records found")
 	errInvalidEventSource = errors.New("invalid event source")
+	errNoObjectCreated    = errors.New("no object created")
+	errBucketNotAllowed   = errors.New("bucket not allowed")
+	errStopped            = errors.New("consumer stopped")
+	errObjectClaimed      = errors.New("object being ingested")
+	errStale              = errors.New("later event already ingested")
 )
 
-// New return an event stream instance from SQS.
-func New(sqsClient *awssqs.ClientSQS, download *downloader.S3Downloader, logger *zap.SugaredLogger, maxMessages int, rfd rfiledata.IFileDataRepository, rmd rmetadata.IMetaDataRepository) (*SQSSource, error) {
-	return &SQSSource{
-		sqs:         sqsClient,
-		download:    download,
-		log:         logger,
-		maxMessages: maxMessages,
-		rFiledata:   rfd,
-		rMetadata:   rmd,
-		wg:          sync.WaitGroup{},
-	}, nil
+// Options represents the optional behaviour of the event stream.
+type Options struct {
+	// HeartbeatInterval is how often the visibility timeout of in-flight messages is extended.
+	// Zero uses half of the visibility timeout of each queue.
+	HeartbeatInterval time.Duration
+	// Backoff delays the redelivery of messages that failed with a transient error.
+	Backoff Backoff
+	// Downloaders is the number of workers downloading files from S3, shared fairly between the queues.
+	Downloaders int
+	// Inserters is the number of workers parsing files and inserting them in postgres, shared fairly between the queues.
+	Inserters int
+	// QueueSize bounds the number of records waiting for each stage of a queue.
+	QueueSize int
+	// Parsers choose the parser of every file. Required.
+	Parsers *parser.Registry
+	// Rules validate the rows before they are persisted. Nil uses validation.Default.
+	Rules validation.Rules
+	// Reports uploads a report of the rejected rows of every file.
+	Reports rejectreport.Options
+	// ClaimTimeout is how long the claim of an object in the ledger lasts, for records that stopped without
+	// releasing it. Zero uses DefaultClaimTimeout.
+	ClaimTimeout time.Duration
+	// ChunkSize is the number of rows inserted at once while a file is streamed. Zero uses record.DefaultChunkSize.
+	ChunkSize int
+	// Rejects is how rows that can't be ingested are handled.
+	Rejects record.Policy
+	// Pipelines are the pipelines queues can target besides DefaultPipeline.
+	Pipelines map[string]Pipeline
+}
+
+// New return an event stream instance from the given SQS queues.
+// The rows, metadata and ledger entry of every file are written in a single transaction of tx.
+func New(queues []Queue, download *downloader.S3Downloader, logger *zap.SugaredLogger, rfd rfiledata.IFileDataRepository, rmd rmetadata.IMetaDataRepository, rl rledger.ILedgerRepository, rr rrejected.IRejectedRepository, tx postgres.ITransactor, opts Options) (*SQSSource, error) {
+	if len(queues) == 0 {
+		return nil, errors.New("no queues configured")
+	}
+
+	pipelines := map[string]Pipeline{DefaultPipeline: {FileData: rfd}}
+	for name, p := range opts.Pipelines {
+		pipelines[name] = p
+	}
+
+	if opts.Parsers == nil {
+		return nil, errors.New("no parsers registered")
+	}
+	if opts.Rules == nil {
+		opts.Rules = validation.Default()
+	}
+	if opts.ClaimTimeout <= 0 {
+		opts.ClaimTimeout = DefaultClaimTimeout
+	}
+
+	s := &SQSSource{
+		download:     download,
+		log:          logger,
+		rMetadata:    rmd,
+		rLedger:      rl,
+		rRejected:    rr,
+		tx:           tx,
+		rejects:      opts.Rejects,
+		rules:        opts.Rules,
+		reports:      opts.Reports,
+		parsers:      opts.Parsers,
+		chunkSize:    opts.ChunkSize,
+		claimTimeout: opts.ClaimTimeout,
+		backoff:      opts.Backoff,
+		decoders:     DefaultDecoders(),
+		wg:           sync.WaitGroup{},
+
+		heartbeatInterval: opts.HeartbeatInterval,
+		done:              make(chan struct{}),
+		stopping:          make(chan struct{}),
+		stopPolling:       func() {},
+		abortWork:         func() {},
+		workCtx:           context.Background(),
+	}
+
+	// every queue gets a fair share of the workers.
+	for i, cfg := range queues {
+		downloaders, inserters := share(opts.Downloaders, len(queues), i), share(opts.Inserters, len(queues), i)
+		q, err := newQueue(cfg, pipelines, newPool(downloaders, inserters, opts.QueueSize), logger)
+		if err != nil {
+			return nil, err
+		}
+		s.queues = append(s.queues, q)
+		s.maxMessages += int(cfg.Client.MaxMessages())
+	}
+
+	return s, nil
+}
+
+// RegisterDecoder appends an envelope decoder to the chain used to read SQS messages.
+func (s *SQSSource) RegisterDecoder(decoder EnvelopeDecoder) {
+	s.decoders = append(s.decoders, decoder)
 }
 
 // Consume opens a channel and sends entities created from SQS messages.
-func (s *SQSSource) Consume() <-chan *domain.Event {
+// Polling stops when ctx is done or Close is called; in-flight work is bound to ctx.
+func (s *SQSSource) Consume(ctx context.Context) <-chan *domain.Event {
+	workCtx, abortWork := context.WithCancel(ctx)
+	pollCtx, stopPolling := context.WithCancel(workCtx)
+	s.mu.Lock()
+	s.abortWork = abortWork
+	s.stopPolling = stopPolling
+	s.workCtx = workCtx
+	s.mu.Unlock()
+
 	out := make(chan *domain.Event, s.maxMessages)
+	for _, q := range s.queues {
+		go s.heartbeat(workCtx, q)
+		q.pool.start(workCtx, s, out)
+		go s.poll(pollCtx, workCtx, q)
+	}
+
 	go func() {
-		for {
-			if s.closed {
-				break
-			}
-			messages, err := s.sqs.GetMessages()
-			if err != nil {
-				s.log.Errorf("Error getting messages from SQS: %v", err)
-				continue
-			}
-			if len(messages) == 0 {
-				s.log.Debug("No messages found from SQS")
-			}
-			for _, msg := range messages {
-				s.processMessage(msg, out)
-			}
-			s.wg.Wait()
+		for _, q := range s.queues {
+			q.pool.wait()
 		}
 		close(out)
 	}()
@@ -80,66 +229,166 @@ func (s *SQSSource) Consume() <-chan *domain.Event {
 	return out
 }
 
-// processMessage read message in queue.
-func (s *SQSSource) processMessage(msg *sqs.Message, out chan *domain.Event) {
-	trackID := createTrackID(msg)
-	logger := s.log.With("trackId", trackID)
+// poll receives messages from a queue until pollCtx is done and dispatches them to the queue's workers.
+func (s *SQSSource) poll(pollCtx, workCtx context.Context, q *queue) {
+	defer q.pool.stop()
+	for pollCtx.Err() == nil {
+		messages, err := q.client.GetMessages(pollCtx)
+		if err != nil {
+			if pollCtx.Err() != nil {
+				break
+			}
+			q.log.Errorf("Error getting messages from SQS: %v", err)
+			continue
+		}
+		if len(messages) == 0 {
+			q.log.Debug("No messages found from SQS")
+		}
+
+		received := make([]*message, 0, len(messages))
+		for _, msg := range messages {
+			m := q.newMessage(msg)
+			q.track(m)
+			received = append(received, m)
+		}
+		for _, m := range received {
+			s.dispatch(workCtx, m)
+		}
+	}
+}
+
+// processMessage read message in queue and fans out one unit of work per S3 record to the worker pool.
+func (s *SQSSource) processMessage(ctx context.Context, m *message) {
+	logger := m.log
 
 	logger.Infof("Step 1 - Start to process SQS event")
 
-	s3Event, err := toS3Event(msg)
+	s3Events, err := toS3Events(m, s.decoders)
+	if errors.Is(err, errNoObjectCreated) {
+		logger.Infof("Step 1 - Skipping message without created objects: %v", err)
+		s.skipMessage(ctx, m)
+		return
+	}
 	if err != nil {
 		logger.Errorf("Error processing message from SQS: %v", err)
-		if err = s.sqs.DeleteMessage(msg); err != nil {
-			logger.Errorf("Error deleting message from SQS: %v", err)
+		s.handleFailure(ctx, m, permanent(err))
+		s.finish(ctx, m, false)
+		return
+	}
+
+	logger.Infof("Step 1 - Message contains %d record(s)", len(s3Events))
+
+	for _, s3Event := range s3Events {
+		s3Event.log = m.queue.log.With("trackId", s3Event.trackID)
+		if !m.queue.allows(s3Event.bucket) {
+			s3Event.log.Errorf("Error processing record from bucket %s: %v", s3Event.bucket, errBucketNotAllowed)
+			s.complete(ctx, s3Event, s3Event.log, permanent(fmt.Errorf("%s: %w", s3Event.bucket, errBucketNotAllowed)))
+			continue
 		}
+		if !m.queue.pool.submit(s3Event) {
+			s.complete(ctx, s3Event, s3Event.log, errStopped)
+		}
+	}
+}
+
+// skipMessage deletes a message carrying nothing to ingest.
+func (s *SQSSource) skipMessage(ctx context.Context, m *message) {
+	m.queue.untrack(m)
+	if err := m.queue.client.DeleteMessage(ctx, m.sqsMessage); err != nil {
+		m.log.Errorf("Deleting of sqs message. %v", err)
+		s.finish(ctx, m, false)
 		return
 	}
+	s.finish(ctx, m, true)
+}
+
+// downloadRecord downloads the file referenced by a single S3 record. It reports whether the record can move on to the insert stage.
+func (s *SQSSource) downloadRecord(ctx context.Context, s3Event *s3Event) bool {
+	logger := s3Event.log
+
+	if s.reports.IsReport(s3Event.bucket, s3Event.key) {
+		logger.Infof("Step 2 - Skipping rejected rows report %s", s3Event.key)
+		s.complete(ctx, s3Event, logger, nil)
+		return false
+	}
+
+	if s.skipStale(ctx, s3Event) || s.claim(ctx, s3Event) {
+		return false
+	}
 
 	logger.Info("Step 2 - Starts the process of downloading the file from S3")
 
-	filename, err := s.download.Download(s3Event.bucket, s3Event.key)
+	object, err := s.download.Head(ctx, s3Event.bucket, s3Event.key, s3Event.versionID)
 	if err != nil {
 		logger.Errorf("Error processing message from SQS in [path = %s]: %v", s3Event.key, err)
-		if err = s.sqs.DeleteMessage(msg); err != nil {
-			logger.Errorf("Error deleting message from SQS in [path = %s]: %v", s3Event.key, err)
+		s.complete(ctx, s3Event, logger, classifyDownload(err))
+		return false
+	}
+	s3Event.object = object
+
+	if tagKey := s.parsers.TagKey(); tagKey != "" {
+		if s3Event.tags, err = s.download.Tags(ctx, s3Event.bucket, s3Event.key, s3Event.versionID); err != nil {
+			logger.Errorf("Error processing message from SQS in [path = %s]: %v", s3Event.key, err)
+			s.complete(ctx, s3Event, logger, classifyDownload(err))
+			return false
 		}
-		return
 	}
 
-	logger.Infof("Step 3 - Event from path: %s", filename)
+	if s.download.Mode() == downloader.Stream {
+		logger.Infof("Step 3 - Event streamed from path: %s", s3Event.key)
+		return true
+	}
 
-	filedata, err := fileMapping(filename, logger)
+	verifier := downloader.NewVerifier(s3Event.fileSize, object)
+	filename, err := s.download.Download(ctx, s3Event.bucket, s3Event.key, s3Event.versionID, verifier)
 	if err != nil {
-		logger.Errorf("Error processing file from CSV in [path = %s]: %v", s3Event.key, err)
-		if err = s.sqs.DeleteMessage(msg); err != nil {
-			logger.Errorf("Error deleting message from SQS in [path = %s]: %v", s3Event.key, err)
+		logger.Errorf("Error processing message from SQS in [path = %s]: %v", s3Event.key, err)
+		if errors.Is(err, downloader.ErrTooLarge) {
+			s.recordFailure(ctx, s3Event, s3Event.name(), record.NewReport(s.rejects))
+			err = permanent(err)
 		}
-		return
+		s.complete(ctx, s3Event, logger, classifyDownload(err))
+		return false
 	}
+	s3Event.filename = filename
+	s3Event.checksum, _ = verifier.Verify()
 
-	if err = s.rFiledata.Insert(filedata); err != nil {
-		logger.Errorf("Error inserting message in FileData: %v", err)
-	}
+	logger.Infof("Step 3 - Event from path: %s", filename)
+	return true
+}
 
-	logger.Info("Step 4 - File saved in postgres: FileData")
+// persistRecord streams a file into postgres chunk by chunk and persists its metadata.
+// The local copy of a downloaded file is deleted once it is read, whatever the outcome.
+func (s *SQSSource) persistRecord(ctx context.Context, s3Event *s3Event, out chan *domain.Event) {
+	logger := s3Event.log
+	filename := s3Event.name()
 
-	metadata := domain.MetaData{
-		TrackID:  trackID,
-		Bucket:   s3Event.bucket,
-		FileName: filename,
-		Key:      s3Event.key,
-		Size:     s3Event.fileSize,
+	report, err := s.ingest(ctx, s3Event, filename)
+	if s3Event.filename != "" {
+		s.deleteLocalFile(s3Event.filename, logger)
 	}
-
-	if err = s.rMetadata.Insert(metadata); err != nil {
-		logger.Errorf("Error inserting message in MetaData: %v", err)
+	if errors.Is(err, errStale) {
+		logger.Infof("Step 4 - A later event of the object was ingested meanwhile, discarding sequencer %s", s3Event.sequencer)
+		s.recordStale(ctx, s3Event)
+		return
+	}
+	if err != nil {
+		logger.Errorf("Error processing file in [path = %s]: %v", s3Event.key, err)
+		if !record.IsFileError(err) {
+			s.complete(ctx, s3Event, logger, err)
+			return
+		}
+		var failed *entryError
+		if errors.As(err, &failed) {
+			s.recordFailure(ctx, failed.entry, failed.entry.entryName, failed.report)
+		}
+		s.recordFailure(ctx, s3Event, filename, report)
+		s.complete(ctx, s3Event, logger, permanent(err))
+		return
 	}
-
-	logger.Info("Step 4 - File saved in postgres: MetaData")
 
 	event := &domain.Event{
-		TrackID:       trackID,
+		TrackID:       s3Event.trackID,
 		File:          s3Event.key,
 		Bucket:        s3Event.bucket,
 		OriginalEvent: s3Event,
@@ -148,82 +397,535 @@ func (s *SQSSource) processMessage(msg *sqs.Message, out chan *domain.Event) {
 		Filename:      filename,
 	}
 	s.wg.Add(1)
-	logger.Infof("Step 5 - Event produced for ID = %s)", trackID)
+	logger.Infof("Step 5 - Event produced for ID = %s)", s3Event.trackID)
 	out <- event
 }
 
-// Processed notify that event of consolidate file was processed.
-func (s *SQSSource) Processed(event *domain.Event) error {
-	defer s.wg.Done()
-	logger := event.Log
+// ingest reads a file into postgres and records its metadata and ledger entry in one transaction, so a file that
+// fails halfway leaves no rows behind and its retry doesn't insert them twice.
+func (s *SQSSource) ingest(ctx context.Context, s3Event *s3Event, filename string) (*record.Report, error) {
+	logger := s3Event.log
+	report := record.NewReport(s.rejects)
 
-	if err := s.download.Delete(event.Filename); err != nil {
-		logger.Errorf("Error deleting local file: %v", err)
-	}
+	reports := &rejectsReports{}
+	s3Event.reports = reports
+	defer reports.discard()
 
-	if s3Event, ok := event.OriginalEvent.(*s3Event); ok {
-		if err := s.sqs.DeleteMessage(s3Event.sqsMessage); err != nil {
-			logger.Errorf("Deleting of sqs message. %v", err)
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		// the sequencer goes first, holding back other events of the object until the file is ingested.
+		if sequencer := s3Event.objectSequencer(); sequencer.Sequencer != "" {
+			advanced, err := s.rLedger.Advance(ctx, sequencer)
+			if err != nil {
+				return fmt.Errorf("error recording sequencer of object in ledger: %w", err)
+			}
+			if !advanced {
+				return errStale
+			}
+		}
+
+		var err error
+		if report, err = s.readFile(ctx, s3Event); err != nil {
 			return err
 		}
-		logger.Infof("Step 6 - Successful deleted sqs message")
+
+		logger.Infof("Step 4 - File saved in postgres: FileData, %d row(s) accepted, %d rejected", report.Accepted, report.Rejected)
+
+		metadata := s3Event.metadata(filename, domain.Ingested)
+		metadata.Accepted, metadata.Rejected = report.Accepted, report.Rejected
+
+		if err = s.rMetadata.Insert(ctx, metadata); err != nil {
+			return fmt.Errorf("error inserting message in MetaData: %w", err)
+		}
+
+		logger.Info("Step 4 - File saved in postgres: MetaData")
+
+		if s3Event.claimed {
+			if err = s.rLedger.Finalise(ctx, s3Event.processedObject()); err != nil {
+				return fmt.Errorf("error finalising object in ledger: %w", err)
+			}
+		}
+
+		// the reports go last, and are deleted if the transaction doesn't commit, so they are only left in S3
+		// along with the rows they were written with.
+		return reports.upload(ctx)
+	})
+	switch {
+	case err == nil:
+	case record.IsFileError(err):
+		// a rejected file leaves no rows, but its reports tell why it was rejected.
+		reports.keep(ctx)
+	default:
+		reports.remove(ctx)
+	}
+	return report, err
+}
+
+// readFile streams the rows of a file to the pipeline of its queue, inserting them in chunks, from its local copy
+// or else straight from S3. Streamed objects are read to the end to check them against S3.
+func (s *SQSSource) readFile(ctx context.Context, s3Event *s3Event) (*record.Report, error) {
+	logger := s3Event.log
+
+	if s3Event.filename != "" {
+		file, err := s.download.Open(s3Event.filename)
+		if err != nil {
+			return record.NewReport(s.rejects), err
+		}
+		defer utils.Close(file, logger)
+
+		info, err := file.Stat()
+		if err != nil {
+			return record.NewReport(s.rejects), err
+		}
+		return s.readSource(ctx, s3Event, file, file, info.Size())
+	}
+
+	verifier := downloader.NewVerifier(s3Event.fileSize, s3Event.object)
+	body, err := s.download.Stream(ctx, s3Event.bucket, s3Event.key, s3Event.versionID, verifier)
+	if err != nil {
+		return record.NewReport(s.rejects), classifyDownload(err)
+	}
+	defer utils.Close(body, logger)
+
+	report, err := s.readSource(ctx, s3Event, interruptedReader{body}, nil, 0)
+	if err != nil && !record.IsFileError(err) {
+		return report, err
+	}
+	// the body is verified before the ingest transaction commits its rows, and before a rejection of the file is
+	// trusted, since corrupted contents may be what the parser rejected.
+	checksum, verifyErr := verifyStream(body, verifier)
+	if verifyErr != nil {
+		return report, verifyErr
+	}
+	s3Event.checksum = checksum
+	return report, err
+}
+
+// readSource reads a file from r, or at of size bytes when it can be read at random. Compressed files are
+// decompressed while they are read, and every entry of a zip archive is read on its own.
+func (s *SQSSource) readSource(ctx context.Context, s3Event *s3Event, r io.Reader, at io.ReaderAt, size int64) (*record.Report, error) {
+	logger := s3Event.log
+	report := record.NewReport(s.rejects)
+
+	br := bufio.NewReader(r)
+	head, err := br.Peek(compression.MagicLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return report, err
+	}
+	codec := compression.Detect(head, s3Event.key, s3Event.object.ContentEncoding)
+	if codec == compression.Zip && s.parsers.Knows(s3Event.key) {
+		// formats built on zip, like xlsx, are read by their parser rather than as archives.
+		codec = compression.None
+	}
+	s3Event.compression = string(codec)
+
+	switch codec {
+	case compression.None:
+		return s.readContents(ctx, s3Event, s3Event.key, br, at, size)
+	case compression.Zip:
+		logger.Info("Step 4 - Reading file as a zip archive")
+		if at == nil {
+			spool, cleanup := s.spooler(br, s3Event.key, logger)
+			defer cleanup()
+			if at, size, err = spool(); err != nil {
+				return report, err
+			}
+		}
+		return s.readArchive(ctx, s3Event, at, size)
+	}
+
+	logger.Infof("Step 4 - Decompressing file as %s", codec)
+	dr, err := compression.NewReader(codec, br)
+	if err != nil {
+		return report, record.Unreadable(err)
+	}
+	defer utils.Close(dr, logger)
+	return s.readContents(ctx, s3Event, compression.TrimExtension(s3Event.key, codec), dr, nil, 0)
+}
+
+// readContents streams uncompressed contents named name to the pipeline of the record with the parser chosen
+// for them. Random access is read from at, which is nil when the contents are a stream and have to be spooled.
+func (s *SQSSource) readContents(ctx context.Context, s3Event *s3Event, name string, r io.Reader, at io.ReaderAt, size int64) (*record.Report, error) {
+	logger := s3Event.log
+	pipeline := s3Event.message.queue.pipeline
+
+	sink := record.NewSink(s.rejects, s.chunkSize, s.rules.Validator(), func(ctx context.Context, chunk []*domain.FileData, rejected []record.Rejection) error {
+		// the chunk is empty when the sink flushes rejected rows only.
+		if len(chunk) > 0 {
+			if err := pipeline.FileData.Insert(ctx, chunk); err != nil {
+				return fmt.Errorf("error inserting message in FileData: %w", err)
+			}
+		}
+		if err := s.rRejected.Insert(ctx, rejectedRows(s3Event.trackID, rejected)); err != nil {
+			return fmt.Errorf("error inserting rejected rows: %w", err)
+		}
+		logger.Debugf("Step 4 - Chunk saved in postgres: %d row(s), %d rejected", len(chunk), len(rejected))
 		return nil
+	})
+
+	spool, cleanup := s.spooler(r, name, logger)
+	defer cleanup()
+
+	in := parser.Input{
+		Bucket:      s3Event.bucket,
+		Key:         s3Event.key,
+		Name:        name,
+		ContentType: s3Event.object.ContentType,
+		Metadata:    s3Event.object.Metadata,
+		Reader:      r,
+		ReaderAt:    at,
+		Size:        size,
+		Spool:       spool,
+	}
+
+	parserName, p, err := s.parsers.Resolve(in, s3Event.tags)
+	if err != nil {
+		return sink.Report(), record.Unreadable(err)
+	}
+	logger.Debugf("Step 4 - Parsing %s with parser %s", name, parserName)
+
+	var rejects *rejectsReport
+	if s.reports.Enabled() {
+		// the report is uploaded by ingest once the rows of the file are written.
+		rejects = s.newRejectsReport(s3Event)
+		s3Event.reports.add(rejects)
+		sink.OnReject(rejects.write)
+	}
+
+	s3Event.dialect, err = p.Parse(ctx, in, sink, logger)
+	if rejects != nil {
+		s3Event.rejectsReport = rejects.location()
+	}
+	return sink.Report(), err
+}
+
+// spooler returns a function copying a stream to a local file for random access, and the function deleting the
+// file once it isn't read anymore.
+func (s *SQSSource) spooler(r io.Reader, name string, logger *zap.SugaredLogger) (func() (io.ReaderAt, int64, error), func()) {
+	cleanups := make([]func(), 0)
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	spool := func() (io.ReaderAt, int64, error) {
+		filename, err := s.download.Spool(unreadableReader{r}, name)
+		if errors.Is(err, downloader.ErrTooLarge) {
+			return nil, 0, record.Unreadable(err)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		cleanups = append(cleanups, func() { s.deleteLocalFile(filename, logger) })
+
+		file, err := s.download.Open(filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		cleanups = append(cleanups, func() { utils.Close(file, logger) })
+
+		info, err := file.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		return file, info.Size(), nil
+	}
+	return spool, cleanup
+}
+
+// recordFailure records the metadata of a file rejected by the reject policy, along with its rejected rows.
+// The chunks of the file were rolled back, so no row counts as accepted and every rejected row is inserted again.
+func (s *SQSSource) recordFailure(ctx context.Context, s3Event *s3Event, filename string, report *record.Report) {
+	metadata := s3Event.metadata(filename, domain.Failed)
+	metadata.Rejected = report.Rejected
+
+	if err := s.rMetadata.Insert(ctx, metadata); err != nil {
+		s3Event.log.Errorf("Error inserting message in MetaData: %v", err)
+	}
+	if err := s.rRejected.Insert(ctx, rejectedRows(s3Event.trackID, report.Rejections)); err != nil {
+		s3Event.log.Errorf("Error inserting rejected rows: %v", err)
+	}
+}
+
+// claim claims the object of a record in the ledger before it is downloaded, so concurrent deliveries of the object
+// don't both ingest it. It reports whether the record is done: the object was already ingested, recording a duplicate
+// outcome, another record is ingesting it, to be retried later, or the ledger could not be checked.
+func (s *SQSSource) claim(ctx context.Context, s3Event *s3Event) bool {
+	logger := s3Event.log
+
+	object := s3Event.processedObject()
+	if object.Fingerprint == "" {
+		return false
+	}
+
+	holder, err := s.rLedger.Claim(ctx, object, time.Now().Add(-s.claimTimeout))
+	if err != nil {
+		logger.Errorf("Error claiming object in ledger: %v", err)
+		s.complete(ctx, s3Event, logger, err)
+		return true
+	}
+	if holder == nil {
+		s3Event.claimed = true
+		return false
+	}
+
+	if holder.Status == domain.ClaimPending {
+		logger.Warnf("Step 2 - Object is being ingested by ID = %s, retrying later", holder.TrackID)
+		s.complete(ctx, s3Event, logger, fmt.Errorf("%w by ID = %s", errObjectClaimed, holder.TrackID))
+		return true
+	}
+
+	logger.Infof("Step 2 - Object already ingested by ID = %s, skipping download", holder.TrackID)
+
+	if err = s.rMetadata.Insert(ctx, s3Event.metadata("", domain.Duplicate)); err != nil {
+		logger.Errorf("Error inserting message in MetaData: %v", err)
+		s.complete(ctx, s3Event, logger, err)
+		return true
+	}
+
+	s.complete(ctx, s3Event, logger, nil)
+	return true
+}
+
+// releaseClaim drops the claim of the object of a record that failed, so its retry or another delivery can ingest it.
+func (s *SQSSource) releaseClaim(ctx context.Context, s3Event *s3Event) {
+	if !s3Event.claimed {
+		return
+	}
+	s3Event.claimed = false
+	if err := s.rLedger.Release(ctx, s3Event.processedObject()); err != nil {
+		s3Event.log.Errorf("Error releasing claim of object in ledger: %v", err)
+	}
+}
+
+// skipStale reports whether a later event of the object of a record was already ingested, or it could not be checked.
+// Either way the record is completed, recording a stale outcome for records older than the ingested one.
+func (s *SQSSource) skipStale(ctx context.Context, s3Event *s3Event) bool {
+	logger := s3Event.log
+
+	if s3Event.sequencer == "" {
+		return false
+	}
+
+	last, err := s.rLedger.LastSequencer(ctx, s3Event.bucket, s3Event.key)
+	if err != nil {
+		logger.Errorf("Error reading sequencer of object from ledger: %v", err)
+		s.complete(ctx, s3Event, logger, err)
+		return true
+	}
+	if last == "" || utils.PadSequencer(s3Event.sequencer) >= last {
+		return false
+	}
+
+	logger.Infof("Step 2 - A later event of the object was already ingested, skipping download of sequencer %s", s3Event.sequencer)
+	s.recordStale(ctx, s3Event)
+	return true
+}
+
+// recordStale completes a record older than the last one ingested for its object, recording a stale outcome.
+func (s *SQSSource) recordStale(ctx context.Context, s3Event *s3Event) {
+	logger := s3Event.log
+
+	s.releaseClaim(ctx, s3Event)
+	if err := s.rMetadata.Insert(ctx, s3Event.metadata("", domain.Stale)); err != nil {
+		logger.Errorf("Error inserting message in MetaData: %v", err)
+		s.complete(ctx, s3Event, logger, err)
+		return
+	}
+
+	s.complete(ctx, s3Event, logger, nil)
+}
+
+// Processed notify that event of consolidate file was processed.
+func (s *SQSSource) Processed(ctx context.Context, event *domain.Event) error {
+	defer s.wg.Done()
+	logger := event.Log
+
+	if s3Event, ok := event.OriginalEvent.(*s3Event); ok {
+		return s.complete(ctx, s3Event, logger, nil)
 	}
 	logger.Warnf("Event isn't sqs message")
 	return nil
 }
 
-// Close the event stream.
-func (s *SQSSource) Close() error {
-	s.closed = true
-	s.wg.Wait()
+// complete records the outcome of a record. Once every record of the message has finished,
+// the message is deleted when all of them succeeded or handed to the failure policy otherwise.
+func (s *SQSSource) complete(ctx context.Context, s3Event *s3Event, logger *zap.SugaredLogger, err error) error {
+	if err != nil {
+		s.releaseClaim(ctx, s3Event)
+	}
+
+	m := s3Event.message
+	finished, failed := m.done(err)
+	if !finished {
+		return nil
+	}
+	if failed > 0 {
+		logger.Warnf("%d record(s) of the message failed", failed)
+		s.handleFailure(ctx, m, m.failure())
+		s.finish(ctx, m, false)
+		return nil
+	}
+
+	m.queue.untrack(m)
+	if err := m.queue.client.DeleteMessage(ctx, m.sqsMessage); err != nil {
+		logger.Errorf("Deleting of sqs message. %v", err)
+		s.finish(ctx, m, false)
+		return err
+	}
+	logger.Infof("Step 6 - Successful deleted sqs message")
+	s.finish(ctx, m, true)
 	return nil
 }
 
-// ---------- Helpers ------------ //
+// workContext returns the context the messages are processed with.
+func (s *SQSSource) workContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.workCtx
+}
+
+// isStopping reports whether Close has been called.
+func (s *SQSSource) isStopping() bool {
+	select {
+	case <-s.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// deleteLocalFile removes the downloaded copy of a file.
+func (s *SQSSource) deleteLocalFile(filename string, logger *zap.SugaredLogger) {
+	if err := s.download.Delete(filename); err != nil {
+		logger.Errorf("Error deleting local file: %v", err)
+	}
+}
+
+// Close stops polling and waits for in-flight work to finish until ctx is done. Work still running at the
+// deadline is aborted and its messages are made visible again so another consumer picks them up right away.
+func (s *SQSSource) Close(ctx context.Context) error {
+	s.mu.Lock()
+	stopPolling, abortWork := s.stopPolling, s.abortWork
+	s.mu.Unlock()
+
+	s.stopOnce.Do(func() { close(s.stopping) })
+	stopPolling()
+	defer s.closeOnce.Do(func() { close(s.done) })
+
+	drained := make(chan struct{})
+	go func() {
+		for _, q := range s.queues {
+			q.pool.wait()
+		}
+		s.wg.Wait()
+		close(drained)
+	}()
 
-func createTrackID(msg *sqs.Message) string {
-	receiveCount := "0"
-	val, ok := msg.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]
-	if ok {
-		receiveCount = *val
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
 	}
-	return fmt.Sprintf("%s-%s", *msg.MessageId, receiveCount)
+
+	abortWork()
+	released := s.releaseInflight()
+	return fmt.Errorf("shutdown deadline exceeded, %d message(s) released: %w", released, ctx.Err())
 }
 
-func toS3Event(msg *sqs.Message) (*s3Event, error) {
-	body := *msg.Body
-	if !gjson.Valid(body) {
-		return nil, errInvalidJSON
+// ---------- Helpers ------------ //
+
+func createTrackID(msg *sqs.Message, index int) string {
+	return fmt.Sprintf("%s-%d", *msg.MessageId, index)
+}
+
+// name returns the name of the file of the record, its local copy when it was downloaded.
+func (e *s3Event) name() string {
+	if e.filename != "" {
+		return e.filename
 	}
+	return path.Base(e.key)
+}
 
-	record := gjson.Get(body, "Records.0")
-	if !record.Exists() {
-		return nil, errNoRecordsFound
+// metadata returns the metadata recorded for the record.
+func (e *s3Event) metadata(filename string, outcome domain.Outcome) *domain.MetaData {
+	return &domain.MetaData{
+		TrackID:       e.trackID,
+		Bucket:        e.bucket,
+		FileName:      filename,
+		Key:           e.key,
+		Size:          e.fileSize,
+		Outcome:       string(outcome),
+		Dialect:       e.dialect,
+		Compression:   e.compression,
+		ParentTrackID: e.parentTrackID,
+		RejectsReport: e.rejectsReport,
+		Checksum:      e.checksum,
+		VersionID:     e.versionID,
+		Sequencer:     e.sequencer,
 	}
+}
 
-	src := record.Get("eventSource")
-	if !src.Exists() || src.String() != "aws:s3" {
-		return nil, fmt.Errorf(`"%v": %w`, src, errInvalidEventSource)
+// objectSequencer returns the sequencer of the record to keep as the last one ingested for its object.
+func (e *s3Event) objectSequencer() *domain.ObjectSequencer {
+	return &domain.ObjectSequencer{
+		Bucket:    e.bucket,
+		Key:       e.key,
+		Sequencer: e.sequencer,
+		TrackID:   e.trackID,
 	}
+}
 
-	return &s3Event{
-		bucket:     record.Get("s3.bucket.name").String(),
-		key:        record.Get("s3.object.key").String(),
-		fileSize:   record.Get("s3.object.size").Int(),
-		sqsMessage: msg,
-	}, nil
+// processedObject returns the ledger entry identifying the object of the record.
+// Its fingerprint is empty when the notification carries neither an ETag nor a sequencer.
+func (e *s3Event) processedObject() *domain.ProcessedObject {
+	fingerprint := e.etag
+	if fingerprint == "" {
+		fingerprint = e.sequencer
+	}
+	return &domain.ProcessedObject{
+		Bucket:      e.bucket,
+		Key:         e.key,
+		VersionID:   e.versionID,
+		Fingerprint: fingerprint,
+		TrackID:     e.trackID,
+	}
 }
 
-func fileMapping(fileName string, logger *zap.SugaredLogger) ([]*domain.FileData, error) {
-	csv, err := csvreader.Read(fileName, logger)
+func toS3Events(m *message, decoders Decoders) ([]*s3Event, error) {
+	records, err := decoders.Decode(*m.sqsMessage.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	filedata := make([]*domain.FileData, 0)
-	for _, row := range csv {
-		filedata = append(filedata, &row)
+	m.expect(len(records))
+	s3Events := make([]*s3Event, 0, len(records))
+	for i, record := range records {
+		s3Events = append(s3Events, &s3Event{
+			trackID:   createTrackID(m.sqsMessage, i),
+			index:     i,
+			bucket:    record.Bucket,
+			key:       record.Key,
+			fileSize:  record.Size,
+			versionID: record.VersionID,
+			etag:      record.ETag,
+			sequencer: record.Sequencer,
+			message:   m,
+		})
+	}
+	return s3Events, nil
+}
+
+func rejectedRows(trackID string, rejections []record.Rejection) []*domain.RejectedRow {
+	rows := make([]*domain.RejectedRow, 0, len(rejections))
+	for _, r := range rejections {
+		rows = append(rows, &domain.RejectedRow{
+			TrackID: trackID,
+			Sheet:   r.Sheet,
+			Line:    r.Line,
+			Raw:     r.Raw,
+			Reason:  r.Reason,
+		})
 	}
-	return filedata, nil
+	return rows
 }
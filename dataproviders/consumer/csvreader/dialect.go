@@ -0,0 +1,276 @@
+package csvreader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Keys of the S3 object metadata that override the dialect of a file.
+const (
+	MetaDelimiter = "csv-delimiter"
+	MetaQuote     = "csv-quote"
+	MetaComment   = "csv-comment"
+	MetaHeader    = "csv-header"
+	MetaTrim      = "csv-trim"
+	MetaCharset   = "csv-charset"
+)
+
+var errInvalidDialect = errors.New("invalid csv dialect")
+
+// Dialect represents how the text of a csv file is written.
+type Dialect struct {
+	// Delimiter separates the fields of a row, "," by default. "tab" stands for a tab.
+	Delimiter string `json:"delimiter,omitempty"`
+	// Quote encloses fields holding delimiters or line breaks, `"` by default.
+	Quote string `json:"quote,omitempty"`
+	// Comment starts lines that are ignored. Empty means the file has no comments.
+	Comment string `json:"comment,omitempty"`
+	// Header tells whether the first row names the columns, true by default.
+	Header *bool `json:"header,omitempty"`
+	// Columns name the columns of files without header, the fields of the mapping in order by default.
+	Columns []string `json:"columns,omitempty"`
+	// Trim removes the spaces surrounding every field.
+	Trim *bool `json:"trim,omitempty"`
+	// Charset is the encoding of the file, transcoded to UTF-8. A byte order mark takes precedence.
+	Charset string `json:"charset,omitempty"`
+}
+
+// DefaultDialect returns the dialect of RFC 4180 files encoded in UTF-8.
+func DefaultDialect() Dialect {
+	header, trim := true, false
+	return Dialect{
+		Delimiter: ",",
+		Quote:     `"`,
+		Header:    &header,
+		Trim:      &trim,
+		Charset:   "utf-8",
+	}
+}
+
+// String returns the dialect as json.
+func (d Dialect) String() string {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// HasHeader reports whether the first row names the columns.
+func (d Dialect) HasHeader() bool {
+	return d.Header == nil || *d.Header
+}
+
+// merge returns d with the settings of o that are set.
+func (d Dialect) merge(o Dialect) Dialect {
+	if o.Delimiter != "" {
+		d.Delimiter = o.Delimiter
+	}
+	if o.Quote != "" {
+		d.Quote = o.Quote
+	}
+	if o.Comment != "" {
+		d.Comment = o.Comment
+	}
+	if o.Header != nil {
+		d.Header = o.Header
+	}
+	if len(o.Columns) > 0 {
+		d.Columns = o.Columns
+	}
+	if o.Trim != nil {
+		d.Trim = o.Trim
+	}
+	if o.Charset != "" {
+		d.Charset = o.Charset
+	}
+	return d
+}
+
+func (d Dialect) delimiter() rune {
+	if d.Delimiter == "tab" {
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(d.Delimiter)
+	return r
+}
+
+func (d Dialect) quote() byte {
+	return d.Quote[0]
+}
+
+func (d Dialect) comment() rune {
+	r, _ := utf8.DecodeRuneInString(d.Comment)
+	if r == utf8.RuneError {
+		return 0
+	}
+	return r
+}
+
+func (d Dialect) validate() error {
+	delimiter := d.delimiter()
+	if d.Delimiter != "tab" && utf8.RuneCountInString(d.Delimiter) != 1 {
+		return fmt.Errorf("%w: delimiter %q must be a single character", errInvalidDialect, d.Delimiter)
+	}
+	if len(d.Quote) != 1 || d.Quote[0] >= utf8.RuneSelf {
+		return fmt.Errorf("%w: quote %q must be a single ascii character", errInvalidDialect, d.Quote)
+	}
+	if d.Comment != "" && utf8.RuneCountInString(d.Comment) != 1 {
+		return fmt.Errorf("%w: comment %q must be a single character", errInvalidDialect, d.Comment)
+	}
+	for _, r := range []rune{delimiter, d.comment()} {
+		if r == '"' || r == rune(d.quote()) || r == '\r' || r == '\n' {
+			return fmt.Errorf("%w: %q can't be used as delimiter or comment", errInvalidDialect, r)
+		}
+	}
+	if delimiter == d.comment() {
+		return fmt.Errorf("%w: delimiter and comment are the same", errInvalidDialect)
+	}
+	if _, err := htmlindex.Get(d.Charset); err != nil {
+		return fmt.Errorf("%w: unknown charset %q", errInvalidDialect, d.Charset)
+	}
+	return nil
+}
+
+// decode returns a reader transcoding r from the charset of the dialect to UTF-8.
+func (d Dialect) decode(r io.Reader) io.Reader {
+	enc, err := htmlindex.Get(d.Charset)
+	if err != nil || enc == unicode.UTF8 {
+		return transform.NewReader(r, unicode.BOMOverride(transform.Nop))
+	}
+	return transform.NewReader(r, unicode.BOMOverride(enc.NewDecoder()))
+}
+
+// DialectRule represents the dialect of the files under a bucket and key prefix.
+type DialectRule struct {
+	// Bucket is the bucket the rule applies to, any bucket when empty.
+	Bucket string `json:"bucket"`
+	// Prefix is the key prefix the rule applies to, any key when empty.
+	Prefix  string  `json:"prefix"`
+	Dialect Dialect `json:"dialect"`
+}
+
+// Dialects are the dialect rules of the consumed buckets.
+type Dialects []DialectRule
+
+// ParseDialects returns the dialect rules described as a json array in raw.
+func ParseDialects(raw string) (Dialects, error) {
+	if raw == "" {
+		return Dialects{}, nil
+	}
+
+	dialects := make(Dialects, 0)
+	if err := json.Unmarshal([]byte(raw), &dialects); err != nil {
+		return nil, fmt.Errorf("invalid csv dialects: %w", err)
+	}
+	for _, rule := range dialects {
+		if err := DefaultDialect().merge(rule.Dialect).validate(); err != nil {
+			return nil, fmt.Errorf("bucket %q prefix %q: %w", rule.Bucket, rule.Prefix, err)
+		}
+	}
+	return dialects, nil
+}
+
+// Resolve returns the effective dialect of an object: the defaults, overridden by the rule with the longest
+// matching prefix, overridden in turn by the csv-* keys of the object metadata.
+func (d Dialects) Resolve(bucket, key string, metadata map[string]string) (Dialect, error) {
+	dialect := DefaultDialect()
+
+	// rules naming the bucket win over rules for any bucket with the same prefix.
+	best, bestScore := -1, -1
+	for i, rule := range d {
+		if (rule.Bucket != "" && rule.Bucket != bucket) || !strings.HasPrefix(key, rule.Prefix) {
+			continue
+		}
+		score := 2 * len(rule.Prefix)
+		if rule.Bucket != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best >= 0 {
+		dialect = dialect.merge(d[best].Dialect)
+	}
+
+	override, err := fromMetadata(metadata)
+	if err != nil {
+		return dialect, err
+	}
+	dialect = dialect.merge(override)
+
+	return dialect, dialect.validate()
+}
+
+// fromMetadata returns the dialect settings found in the metadata of an S3 object.
+func fromMetadata(metadata map[string]string) (Dialect, error) {
+	dialect := Dialect{}
+	for k, v := range metadata {
+		switch strings.ToLower(k) {
+		case MetaDelimiter:
+			dialect.Delimiter = v
+		case MetaQuote:
+			dialect.Quote = v
+		case MetaComment:
+			dialect.Comment = v
+		case MetaCharset:
+			dialect.Charset = v
+		case MetaHeader, MetaTrim:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return dialect, fmt.Errorf("%w: metadata %s: %v", errInvalidDialect, k, err)
+			}
+			if strings.ToLower(k) == MetaHeader {
+				dialect.Header = &b
+			} else {
+				dialect.Trim = &b
+			}
+		}
+	}
+	return dialect, nil
+}
+
+// quoteSwapper exchanges two bytes of a stream, so encoding/csv reads a custom quote character as `"`.
+type quoteSwapper struct {
+	r    io.Reader
+	a, b byte
+}
+
+func (s quoteSwapper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	for i := 0; i < n; i++ {
+		p[i] = s.swap(p[i])
+	}
+	return n, err
+}
+
+func (s quoteSwapper) swap(c byte) byte {
+	switch c {
+	case s.a:
+		return s.b
+	case s.b:
+		return s.a
+	}
+	return c
+}
+
+func (s quoteSwapper) swapString(v string) string {
+	if strings.IndexByte(v, s.a) < 0 && strings.IndexByte(v, s.b) < 0 {
+		return v
+	}
+	buf := []byte(v)
+	for i := range buf {
+		buf[i] = s.swap(buf[i])
+	}
+	return string(buf)
+}
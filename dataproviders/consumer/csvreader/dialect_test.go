@@ -0,0 +1,98 @@
+package csvreader
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDialectsResolve(t *testing.T) {
+	yes, no := true, false
+	dialects := Dialects{
+		{Prefix: "", Dialect: Dialect{Delimiter: ";"}},
+		{Prefix: "feeds/", Dialect: Dialect{Delimiter: "|", Comment: "#"}},
+		{Prefix: "feeds/latin/", Dialect: Dialect{Charset: "latin1"}},
+		{Bucket: "mainframe", Prefix: "feeds/", Dialect: Dialect{Delimiter: "tab"}},
+		{Bucket: "other", Prefix: "feeds/latin/extra/", Dialect: Dialect{Quote: "'"}},
+	}
+
+	tests := []struct {
+		name     string
+		bucket   string
+		key      string
+		metadata map[string]string
+		want     Dialect
+		wantErr  error
+	}{
+		{
+			name:   "rule for any key",
+			bucket: "bucket",
+			key:    "file.csv",
+			want:   Dialect{Delimiter: ";"},
+		},
+		{
+			name:   "longest prefix wins",
+			bucket: "bucket",
+			key:    "feeds/latin/file.csv",
+			want:   Dialect{Charset: "latin1"},
+		},
+		{
+			name:   "bucket rule wins over any bucket with the same prefix",
+			bucket: "mainframe",
+			key:    "feeds/file.csv",
+			want:   Dialect{Delimiter: "tab"},
+		},
+		{
+			name:   "longer prefix wins over the bucket rule",
+			bucket: "mainframe",
+			key:    "feeds/latin/file.csv",
+			want:   Dialect{Charset: "latin1"},
+		},
+		{
+			name:   "rule of another bucket",
+			bucket: "bucket",
+			key:    "feeds/latin/extra/file.csv",
+			want:   Dialect{Charset: "latin1"},
+		},
+		{
+			name:   "metadata overrides the rule",
+			bucket: "bucket",
+			key:    "feeds/file.csv",
+			metadata: map[string]string{
+				"CSV-Delimiter": ",",
+				"csv-header":    "false",
+				"csv-trim":      "true",
+				"owner":         "ventas",
+			},
+			want: Dialect{Delimiter: ",", Comment: "#", Header: &no, Trim: &yes},
+		},
+		{
+			name:     "metadata with a bad boolean",
+			bucket:   "bucket",
+			key:      "file.csv",
+			metadata: map[string]string{"csv-header": "maybe"},
+			wantErr:  errInvalidDialect,
+		},
+		{
+			name:     "metadata making the dialect invalid",
+			bucket:   "bucket",
+			key:      "feeds/file.csv",
+			metadata: map[string]string{"csv-delimiter": "#"},
+			wantErr:  errInvalidDialect,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dialects.Resolve(tt.bucket, tt.key, tt.metadata)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if want := DefaultDialect().merge(tt.want); got.String() != want.String() {
+				t.Errorf("Resolve() = %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package csvreader
+
+import (
+	"context"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Name is the name of the csv parser.
+const Name = "csv"
+
+var (
+	// Extensions are the extensions of csv files.
+	Extensions = []string{".csv", ".txt", ".tsv"}
+	// ContentTypes are the media types of csv files.
+	ContentTypes = []string{"text/csv", "text/plain", "text/tab-separated-values"}
+)
+
+// Parser reads csv files written in the dialect of their bucket, prefix and metadata.
+type Parser struct {
+	dialects Dialects
+	mapping  mapping.Mapping
+}
+
+// NewParser returns a csv parser.
+func NewParser(dialects Dialects, m mapping.Mapping) *Parser {
+	return &Parser{dialects: dialects, mapping: m}
+}
+
+// Parse streams the rows of a csv file to sink and returns its dialect.
+func (p *Parser) Parse(ctx context.Context, in parser.Input, sink *record.Sink, logger *zap.SugaredLogger) (string, error) {
+	dialect, err := p.dialects.Resolve(in.Bucket, in.Key, in.Metadata)
+	if err != nil {
+		return "", record.Unreadable(err)
+	}
+	logger.Infof("Step 4 - Reading file as CSV with dialect %s", dialect)
+	return dialect.String(), Read(ctx, in.Reader, dialect, p.mapping, sink, logger)
+}
@@ -1,61 +1,131 @@
 package csvreader
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
 	"service-worker-sqs-s3-postgres/core/domain"
-	"service-worker-sqs-s3-postgres/dataproviders/utils"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
 	"strings"
 
-	"github.com/spf13/afero"
 	"go.uber.org/zap"
 )
 
-const numberColumns = 3
+var errFieldCount = errors.New("wrong number of fields")
 
-// Read returns a list of filedata to be persisted in the database .
-func Read(fileName string, logger *zap.SugaredLogger) ([]domain.FileData, error) {
-	filedata := make([]domain.FileData, 0)
-
-	a := afero.Afero{
-		Fs: afero.NewOsFs(),
+// Read streams the rows of the csv in r, written in dialect, to sink. Columns are matched to filedata by the header.
+func Read(ctx context.Context, r io.Reader, dialect Dialect, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	if err := dialect.validate(); err != nil {
+		return record.Unreadable(err)
 	}
 
-	contents, err := a.ReadFile(fileName)
-	if err != nil {
-		return filedata, err
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var binding *mapping.Binding
+	var columns int
+	if !dialect.HasHeader() {
+		header := dialect.Columns
+		if len(header) == 0 {
+			header = m.Fields()
+		}
+		var err error
+		if binding, err = m.Bind(header); err != nil {
+			return record.Unreadable(err)
+		}
+		columns = len(header)
 	}
 
-	for rec := range ProcessCSV(strings.NewReader(string(contents)), logger) {
-		if len(rec) == numberColumns {
-			filedata = columnsToFileData(rec, filedata)
+	decode := func(row record.Row) (*domain.FileData, error) {
+		if binding == nil {
+			var err error
+			if binding, err = m.Bind(row.Fields); err != nil {
+				return nil, record.Unreadable(err)
+			}
+			columns = len(row.Fields)
+			return nil, nil
+		}
+		if len(row.Fields) != columns {
+			return nil, fmt.Errorf("%w: expected %d, got %d", errFieldCount, columns, len(row.Fields))
 		}
+		return binding.FileData(row.Fields)
 	}
-	return filedata, nil
+	return sink.Consume(ctx, ProcessCSV(ctx, r, dialect, logger), decode, logger)
 }
 
-// ProcessCSV returns a channel for reading data associated with the csv .
-func ProcessCSV(rc io.Reader, logger *zap.SugaredLogger) (ch chan []string) {
-	ch = make(chan []string)
+// ProcessCSV returns a channel for reading the rows of the csv, written in dialect, until ctx is done.
+// When the dialect has a header it is the first row. Malformed rows carry their error, and errors that
+// stop the reading are wrapped with record.Unreadable.
+func ProcessCSV(ctx context.Context, rc io.Reader, dialect Dialect, logger *zap.SugaredLogger) (ch chan record.Row) {
+	ch = make(chan record.Row)
 
 	go func() {
+		defer close(ch)
+		rc = dialect.decode(rc)
+		swapper := quoteSwapper{a: '"', b: dialect.quote()}
+		if swapper.b != '"' {
+			rc = quoteSwapper{r: rc, a: swapper.a, b: swapper.b}
+		}
+
 		r := csv.NewReader(rc)
 		r.LazyQuotes = true
+		r.FieldsPerRecord = -1
+		r.Comma = dialect.delimiter()
+		r.Comment = dialect.comment()
+		r.TrimLeadingSpace = dialect.Trim != nil && *dialect.Trim
 
-		if _, err := r.Read(); err != nil { // read header
-			logger.Fatal(err)
+		fields := func(rec []string) []string {
+			for i, v := range rec {
+				v = swapper.swapString(v)
+				if r.TrimLeadingSpace {
+					v = strings.TrimSpace(v)
+				}
+				rec[i] = v
+			}
+			return rec
 		}
-		defer close(ch)
-		for {
-			rec, err := r.Read()
+
+		if dialect.HasHeader() {
+			header, err := r.Read()
 			if err != nil {
 				if errors.Is(err, io.EOF) {
-					break
+					err = record.ErrEmptyFile
 				}
-				logger.Fatal(err)
+				logger.Errorf("Error reading header of CSV: %v", err)
+				record.Send(ctx, ch, record.Row{Line: 1, Err: record.Unreadable(err)})
+				return
+			}
+			line, _ := r.FieldPos(0)
+			header = fields(header)
+			if !record.Send(ctx, ch, record.Row{Line: line, Raw: toRaw(header, dialect), Fields: header}) {
+				return
+			}
+		}
+		for {
+			rec, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			rec = fields(rec)
+			row := record.Row{Fields: rec, Raw: toRaw(rec, dialect)}
+			var parseErr *csv.ParseError
+			switch {
+			case errors.As(err, &parseErr):
+				row.Line, row.Err = parseErr.StartLine, parseErr.Err
+			case err != nil:
+				row.Err = record.Unreadable(err)
+			default:
+				row.Line, _ = r.FieldPos(0)
+			}
+
+			if !record.Send(ctx, ch, row) || errors.Is(row.Err, record.ErrUnreadable) {
+				return
 			}
-			ch <- rec
 		}
 	}()
 	return
@@ -63,11 +133,14 @@ func ProcessCSV(rc io.Reader, logger *zap.SugaredLogger) (ch chan []string) {
 
 // ---------- Helpers ------------ //
 
-func columnsToFileData(rec []string, info []domain.FileData) []domain.FileData {
-	filedata := domain.FileData{
-		ID:      utils.StringToInt64(rec[0]),
-		Message: rec[1],
-		Owner:   rec[2],
+func toRaw(rec []string, dialect Dialect) string {
+	if len(rec) == 0 {
+		return ""
 	}
-	return append(info, filedata)
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = dialect.delimiter()
+	_ = w.Write(rec)
+	w.Flush()
+	return strings.TrimRight(buf.String(), "\n")
 }
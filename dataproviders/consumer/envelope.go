@@ -0,0 +1,158 @@
+package consumer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Record represents an S3 object notification extracted from a message envelope.
+type Record struct {
+	Bucket    string
+	Key       string
+	Size      int64
+	VersionID string
+	ETag      string
+	Sequencer string
+}
+
+// EnvelopeDecoder extracts the S3 records carried by a message envelope.
+type EnvelopeDecoder interface {
+	// Decode returns the records in body. ok is false when the decoder does not recognise the envelope.
+	// Decoders that wrap other envelopes use chain to decode their payload.
+	Decode(body gjson.Result, chain Decoders) (records []Record, ok bool, err error)
+}
+
+// Decoders is a chain of envelope decoders tried in order.
+type Decoders []EnvelopeDecoder
+
+// DefaultDecoders returns the chain for raw S3, SNS-wrapped S3 and EventBridge notifications.
+func DefaultDecoders() Decoders {
+	return Decoders{
+		S3Decoder{},
+		SNSDecoder{},
+		EventBridgeDecoder{},
+	}
+}
+
+// Decode returns the records of the first decoder that recognises the body.
+func (d Decoders) Decode(body string) ([]Record, error) {
+	if !gjson.Valid(body) {
+		return nil, errInvalidJSON
+	}
+
+	doc := gjson.Parse(body)
+	for _, decoder := range d {
+		records, ok, err := decoder.Decode(doc, d)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, errNoRecordsFound
+		}
+		return records, nil
+	}
+	return nil, errInvalidEventSource
+}
+
+// S3Decoder decodes a raw S3 event notification.
+type S3Decoder struct{}
+
+// Decode implements EnvelopeDecoder. Only ObjectCreated records are decoded; removals and the test event S3 sends
+// when notifications are set up are skipped.
+func (S3Decoder) Decode(body gjson.Result, _ Decoders) ([]Record, bool, error) {
+	if event := body.Get("Event").String(); event == "s3:TestEvent" {
+		return nil, true, fmt.Errorf(`"%v": %w`, event, errNoObjectCreated)
+	}
+
+	rs := body.Get("Records")
+	if !rs.IsArray() {
+		return nil, false, nil
+	}
+
+	records := make([]Record, 0)
+	skipped := 0
+	for _, r := range rs.Array() {
+		src := r.Get("eventSource")
+		if !src.Exists() || src.String() != "aws:s3" {
+			return nil, true, fmt.Errorf(`"%v": %w`, src, errInvalidEventSource)
+		}
+		if !strings.HasPrefix(r.Get("eventName").String(), "ObjectCreated:") {
+			skipped++
+			continue
+		}
+		records = append(records, Record{
+			Bucket:    r.Get("s3.bucket.name").String(),
+			Key:       unescapeKey(r.Get("s3.object.key").String()),
+			Size:      r.Get("s3.object.size").Int(),
+			VersionID: r.Get("s3.object.versionId").String(),
+			ETag:      r.Get("s3.object.eTag").String(),
+			Sequencer: r.Get("s3.object.sequencer").String(),
+		})
+	}
+	if len(records) == 0 && skipped > 0 {
+		return nil, true, fmt.Errorf("%d record(s): %w", skipped, errNoObjectCreated)
+	}
+	return records, true, nil
+}
+
+// SNSDecoder unwraps an SNS notification and decodes its message with the chain.
+type SNSDecoder struct{}
+
+// Decode implements EnvelopeDecoder.
+func (SNSDecoder) Decode(body gjson.Result, chain Decoders) ([]Record, bool, error) {
+	if body.Get("Type").String() != "Notification" {
+		return nil, false, nil
+	}
+
+	message := body.Get("Message")
+	if message.Type != gjson.String {
+		return nil, true, fmt.Errorf("sns message: %w", errInvalidJSON)
+	}
+
+	records, err := chain.Decode(message.String())
+	return records, true, err
+}
+
+// EventBridgeDecoder decodes an EventBridge "Object Created" event.
+type EventBridgeDecoder struct{}
+
+// Decode implements EnvelopeDecoder.
+func (EventBridgeDecoder) Decode(body gjson.Result, _ Decoders) ([]Record, bool, error) {
+	if !body.Get("detail-type").Exists() || !body.Get("detail").IsObject() {
+		return nil, false, nil
+	}
+
+	src := body.Get("source").String()
+	if src != "aws.s3" {
+		return nil, true, fmt.Errorf(`"%v": %w`, src, errInvalidEventSource)
+	}
+
+	if detailType := body.Get("detail-type").String(); detailType != "Object Created" {
+		return nil, true, fmt.Errorf(`"%v": %w`, detailType, errNoObjectCreated)
+	}
+
+	detail := body.Get("detail")
+	return []Record{{
+		Bucket:    detail.Get("bucket.name").String(),
+		Key:       detail.Get("object.key").String(),
+		Size:      detail.Get("object.size").Int(),
+		VersionID: detail.Get("object.version-id").String(),
+		ETag:      detail.Get("object.etag").String(),
+		Sequencer: detail.Get("object.sequencer").String(),
+	}}, true, nil
+}
+
+// unescapeKey decodes the URL-encoded object key of S3 notifications.
+func unescapeKey(key string) string {
+	unescaped, err := url.QueryUnescape(key)
+	if err != nil {
+		return key
+	}
+	return unescaped
+}
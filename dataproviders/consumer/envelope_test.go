@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const s3Body = `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},` +
+	`"object":{"key":"files/file+test%281%29.csv","size":42,"versionId":"v1","eTag":"etag","sequencer":"0055"}}}]}`
+
+func TestDecodersDecode(t *testing.T) {
+	record := Record{
+		Bucket:    "bucket",
+		Key:       "files/file test(1).csv",
+		Size:      42,
+		VersionID: "v1",
+		ETag:      "etag",
+		Sequencer: "0055",
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		want    []Record
+		wantErr error
+	}{
+		{
+			name: "raw s3",
+			body: s3Body,
+			want: []Record{record},
+		},
+		{
+			name: "sns wrapping s3",
+			body: `{"Type":"Notification","Message":` + quote(s3Body) + `}`,
+			want: []Record{record},
+		},
+		{
+			name: "sns wrapping sns",
+			body: `{"Type":"Notification","Message":` + quote(`{"Type":"Notification","Message":`+quote(s3Body)+`}`) + `}`,
+			want: []Record{record},
+		},
+		{
+			name: "eventbridge",
+			body: `{"source":"aws.s3","detail-type":"Object Created","detail":{"bucket":{"name":"bucket"},` +
+				`"object":{"key":"files/file test(1).csv","size":42,"version-id":"v1","etag":"etag","sequencer":"0055"}}}`,
+			want: []Record{record},
+		},
+		{
+			name: "several records",
+			body: `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"a"},"object":{"key":"1.csv"}}},` +
+				`{"eventSource":"aws:s3","eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"a"},"object":{"key":"2.csv"}}},` +
+				`{"eventSource":"aws:s3","eventName":"ObjectCreated:Copy","s3":{"bucket":{"name":"b"},"object":{"key":"3.csv"}}}]}`,
+			want: []Record{{Bucket: "a", Key: "1.csv"}, {Bucket: "b", Key: "3.csv"}},
+		},
+		{
+			name:    "invalid json",
+			body:    `{"Records":`,
+			wantErr: errInvalidJSON,
+		},
+		{
+			name:    "sns message that isn't a string",
+			body:    `{"Type":"Notification","Message":{"Records":[]}}`,
+			wantErr: errInvalidJSON,
+		},
+		{
+			name:    "sns wrapping invalid json",
+			body:    `{"Type":"Notification","Message":"{\"Records\":"}`,
+			wantErr: errInvalidJSON,
+		},
+		{
+			name:    "s3 test event",
+			body:    `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"bucket"}`,
+			wantErr: errNoObjectCreated,
+		},
+		{
+			name:    "only removals",
+			body:    `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"a"},"object":{"key":"1.csv"}}}]}`,
+			wantErr: errNoObjectCreated,
+		},
+		{
+			name:    "no records",
+			body:    `{"Records":[]}`,
+			wantErr: errNoRecordsFound,
+		},
+		{
+			name:    "record of another source",
+			body:    `{"Records":[{"eventSource":"aws:sqs"}]}`,
+			wantErr: errInvalidEventSource,
+		},
+		{
+			name:    "eventbridge of another source",
+			body:    `{"source":"aws.ec2","detail-type":"Object Created","detail":{}}`,
+			wantErr: errInvalidEventSource,
+		},
+		{
+			name:    "eventbridge deletion",
+			body:    `{"source":"aws.s3","detail-type":"Object Deleted","detail":{"bucket":{"name":"a"}}}`,
+			wantErr: errNoObjectCreated,
+		},
+		{
+			name:    "unknown envelope",
+			body:    `{"hello":"world"}`,
+			wantErr: errInvalidEventSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DefaultDecoders().Decode(tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// quote returns s as a json string, the way SNS wraps the message it delivers.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
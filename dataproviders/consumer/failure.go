@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Message attributes added to the messages sent to the dead-letter queue.
+const (
+	attrErrorMessage    = "ErrorMessage"
+	attrErrorKind       = "ErrorKind"
+	attrSourceQueue     = "SourceQueue"
+	attrSourceMessageID = "SourceMessageId"
+	attrFailedAt        = "FailedAt"
+	attrReceiveCount    = "ReceiveCount"
+)
+
+// permanentError marks a failure that redelivering the message can't fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks err as a permanent failure.
+func permanent(err error) error {
+	if err == nil || isPermanent(err) {
+		return err
+	}
+	return &permanentError{err: err}
+}
+
+// isPermanent reports whether err is a permanent failure.
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// failureKind returns the name of the failure class of err.
+func failureKind(err error) string {
+	if isPermanent(err) {
+		return "permanent"
+	}
+	return "transient"
+}
+
+// classifyDownload marks the S3 errors that won't succeed on a retry as permanent.
+func classifyDownload(err error) error {
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		switch reqErr.StatusCode() {
+		case http.StatusNotFound, http.StatusForbidden:
+			return permanent(err)
+		}
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		switch awsErr.Code() {
+		case s3.ErrCodeNoSuchKey, s3.ErrCodeNoSuchBucket, "AccessDenied":
+			return permanent(err)
+		}
+	}
+	return err
+}
+
+// handleFailure applies the failure policy to a message: transient failures are retried with backoff
+// until the message runs out of attempts, permanent failures are moved to the dead-letter queue when one is configured.
+// The message stops being tracked first, so the heartbeat doesn't undo the visibility set by the policy.
+func (s *SQSSource) handleFailure(ctx context.Context, m *message, err error) {
+	tracked := m.queue.untrack(m)
+	if ctx.Err() != nil || s.isStopping() {
+		// shutting down: hand the message to another consumer instead of holding it back.
+		if tracked {
+			s.release(m)
+		}
+		return
+	}
+
+	if isPermanent(err) {
+		s.deadLetterMessage(ctx, m, err)
+		return
+	}
+
+	attempt := receiveCount(m.sqsMessage)
+	if s.backoff.Exhausted(attempt) {
+		s.giveUp(ctx, m, fmt.Errorf("giving up after %d attempts: %w", attempt, err))
+		return
+	}
+
+	delay := s.backoff.Delay(attempt)
+	if delay <= 0 {
+		m.log.Warnf("Message kept for redelivery after transient failure: %v", err)
+		return
+	}
+
+	if err := m.queue.client.ChangeMessageVisibility(ctx, m.sqsMessage, int64(delay.Seconds())); err != nil {
+		m.log.Errorf("Error delaying redelivery of message: %v", err)
+		return
+	}
+	m.log.Warnf("Message will be retried in %s after attempt %d failed: %v", delay.Round(time.Second), attempt, err)
+}
+
+// giveUp applies the configured give up policy to a message that exhausted its attempts.
+func (s *SQSSource) giveUp(ctx context.Context, m *message, err error) {
+	switch s.backoff.GiveUp {
+	case GiveUpDeadLetter:
+		s.deadLetterMessage(ctx, m, err)
+	case GiveUpDelete:
+		if err := m.queue.client.DeleteMessage(ctx, m.sqsMessage); err != nil {
+			m.log.Errorf("Error deleting message from SQS: %v", err)
+			return
+		}
+		m.log.Warnf("Message deleted: %v", err)
+	default:
+		m.log.Warnf("Message kept in queue: %v", err)
+	}
+}
+
+// deadLetterMessage moves a message to the dead-letter queue, or keeps it in the queue when none is configured.
+func (s *SQSSource) deadLetterMessage(ctx context.Context, m *message, err error) {
+	deadLetter := m.queue.deadLetter
+	if deadLetter == nil {
+		m.log.Warnf("Message kept in queue, no dead-letter queue configured: %v", err)
+		return
+	}
+
+	msg := m.sqsMessage
+	group := messageGroupID(msg)
+	if group == "" {
+		group = defaultDeadLetterGroup
+	}
+
+	if err := deadLetter.SendMessage(ctx, *msg.Body, failureAttributes(msg, m.queue.client.URL(), err), group, aws.StringValue(msg.MessageId)); err != nil {
+		m.log.Errorf("Error sending message to dead-letter queue: %v", err)
+		return
+	}
+
+	if err := m.queue.client.DeleteMessage(ctx, msg); err != nil {
+		m.log.Errorf("Error deleting message from SQS: %v", err)
+		return
+	}
+	m.log.Infof("Message moved to dead-letter queue %s", deadLetter.URL())
+}
+
+// failureAttributes returns the message attributes describing the failure of msg.
+func failureAttributes(msg *sqs.Message, queue string, err error) map[string]*sqs.MessageAttributeValue {
+	attrs := map[string]*sqs.MessageAttributeValue{
+		attrErrorMessage:    stringAttribute(err.Error()),
+		attrErrorKind:       stringAttribute(failureKind(err)),
+		attrSourceQueue:     stringAttribute(queue),
+		attrSourceMessageID: stringAttribute(aws.StringValue(msg.MessageId)),
+		attrFailedAt:        stringAttribute(time.Now().UTC().Format(time.RFC3339)),
+	}
+	attrs[attrReceiveCount] = &sqs.MessageAttributeValue{
+		DataType:    aws.String("Number"),
+		StringValue: aws.String(strconv.Itoa(receiveCount(msg))),
+	}
+	return attrs
+}
+
+func stringAttribute(value string) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
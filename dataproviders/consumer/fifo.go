@@ -0,0 +1,101 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// defaultDeadLetterGroup is the message group used in a FIFO dead-letter queue for messages without one.
+const defaultDeadLetterGroup = "dead-letter"
+
+// groups serialises the processing of FIFO messages that share a MessageGroupId,
+// while messages of different groups run in parallel.
+type groups struct {
+	mu      sync.Mutex
+	pending map[string][]*message
+}
+
+func newGroups() *groups {
+	return &groups{
+		pending: make(map[string][]*message),
+	}
+}
+
+// acquire reports whether m can start now. Otherwise it is queued behind the active message of its group.
+func (g *groups) acquire(group string, m *message) bool {
+	if group == "" {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	queue, active := g.pending[group]
+	if !active {
+		g.pending[group] = nil
+		return true
+	}
+	g.pending[group] = append(queue, m)
+	return false
+}
+
+// next finishes the active message of a group and returns the next message to process, if any.
+// When the active message failed the queued messages are dropped, so SQS redelivers the group in order.
+func (g *groups) next(group string, succeeded bool) (*message, []*message) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	queue := g.pending[group]
+	if !succeeded || len(queue) == 0 {
+		delete(g.pending, group)
+		return nil, queue
+	}
+	g.pending[group] = queue[1:]
+	return queue[0], nil
+}
+
+// dispatch starts processing m unless another message of its FIFO group is still in flight.
+func (s *SQSSource) dispatch(ctx context.Context, m *message) {
+	if m.queue.groups.acquire(m.queue.group(m), m) {
+		s.processMessage(ctx, m)
+	}
+}
+
+// finish releases the FIFO group of a handled message and starts the next message of the group. The next message
+// runs on the work context of the source, like the messages dispatched by the poller, and Close waits for it.
+func (s *SQSSource) finish(ctx context.Context, m *message, succeeded bool) {
+	group := m.queue.group(m)
+	if group == "" {
+		return
+	}
+
+	next, dropped := m.queue.groups.next(group, succeeded)
+	for _, d := range dropped {
+		d.log.Warnf("Message of failed group %s released to keep its order", group)
+		d.queue.untrack(d)
+		s.release(d)
+	}
+	if next != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.processMessage(s.workContext(), next)
+		}()
+	}
+}
+
+// group returns the FIFO group of a message, or empty when its queue isn't a FIFO queue.
+func (q *queue) group(m *message) string {
+	if !q.client.IsFIFO() {
+		return ""
+	}
+	return messageGroupID(m.sqsMessage)
+}
+
+// messageGroupID returns the FIFO group of a message, or empty for standard queues.
+func messageGroupID(msg *sqs.Message) string {
+	return aws.StringValue(msg.Attributes[sqs.MessageSystemAttributeNameMessageGroupId])
+}
@@ -0,0 +1,121 @@
+package consumer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// step is an acquire of message id in group, or the end of the active message of group when id is empty.
+type step struct {
+	group     string
+	id        string
+	succeeded bool
+	// wantStart is whether an acquired message starts now.
+	wantStart bool
+	// wantNext and wantDropped are the messages returned when the active message of group ends.
+	wantNext    string
+	wantDropped []string
+}
+
+func TestGroupsOrdering(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "messages of a group run one after the other in order",
+			steps: []step{
+				{group: "a", id: "1", wantStart: true},
+				{group: "a", id: "2"},
+				{group: "a", id: "3"},
+				{group: "a", succeeded: true, wantNext: "2"},
+				{group: "a", succeeded: true, wantNext: "3"},
+				{group: "a", succeeded: true},
+				{group: "a", id: "4", wantStart: true},
+			},
+		},
+		{
+			name: "groups run in parallel",
+			steps: []step{
+				{group: "a", id: "1", wantStart: true},
+				{group: "b", id: "2", wantStart: true},
+				{group: "a", id: "3"},
+				{group: "b", succeeded: true},
+				{group: "b", id: "4", wantStart: true},
+				{group: "a", succeeded: true, wantNext: "3"},
+			},
+		},
+		{
+			name: "a failure drops the queued messages of its group only",
+			steps: []step{
+				{group: "a", id: "1", wantStart: true},
+				{group: "a", id: "2"},
+				{group: "a", id: "3"},
+				{group: "b", id: "4", wantStart: true},
+				{group: "b", id: "5"},
+				{group: "a", succeeded: false, wantDropped: []string{"2", "3"}},
+				{group: "a", id: "6", wantStart: true},
+				{group: "b", succeeded: true, wantNext: "5"},
+			},
+		},
+		{
+			name: "messages without group start right away",
+			steps: []step{
+				{group: "", id: "1", wantStart: true},
+				{group: "", id: "2", wantStart: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGroups()
+			for i, s := range tt.steps {
+				if s.id != "" {
+					if got := g.acquire(s.group, newTestMessage(s.id, s.group)); got != s.wantStart {
+						t.Fatalf("step %d: acquire(%s, %s) = %v, want %v", i, s.group, s.id, got, s.wantStart)
+					}
+					continue
+				}
+
+				next, dropped := g.next(s.group, s.succeeded)
+				if got := messageID(next); got != s.wantNext {
+					t.Fatalf("step %d: next(%s) = %q, want %q", i, s.group, got, s.wantNext)
+				}
+				if got := messageIDs(dropped); !reflect.DeepEqual(got, s.wantDropped) {
+					t.Fatalf("step %d: next(%s) dropped %v, want %v", i, s.group, got, s.wantDropped)
+				}
+			}
+		})
+	}
+}
+
+func newTestMessage(id, group string) *message {
+	return &message{sqsMessage: &sqs.Message{
+		MessageId: aws.String(id),
+		Attributes: map[string]*string{
+			sqs.MessageSystemAttributeNameMessageGroupId: aws.String(group),
+		},
+	}}
+}
+
+func messageID(m *message) string {
+	if m == nil {
+		return ""
+	}
+	return aws.StringValue(m.sqsMessage.MessageId)
+}
+
+func messageIDs(ms []*message) []string {
+	if len(ms) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(ms))
+	for _, m := range ms {
+		ids = append(ids, messageID(m))
+	}
+	return ids
+}
@@ -0,0 +1,137 @@
+package fixedwidthreader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// FieldType is how the text of a field is parsed.
+type FieldType string
+
+const (
+	// String fields are kept as written, without the padding spaces.
+	String FieldType = "string"
+	// Integer fields are whole numbers, padded with spaces or zeros.
+	Integer FieldType = "integer"
+	// Decimal fields are numbers with a decimal point, or with Scale implied decimal places when written without it.
+	Decimal FieldType = "decimal"
+)
+
+var (
+	number = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+	errInvalidLayout = errors.New("invalid fixed-width layout")
+	errInvalidNumber = errors.New("invalid number")
+)
+
+// Field represents a field of a fixed-width record.
+type Field struct {
+	Name string `json:"name"`
+	// Start is the position of the first character of the field, starting at 1.
+	Start  int       `json:"start"`
+	Length int       `json:"length"`
+	Type   FieldType `json:"type,omitempty"`
+	// Scale is the number of implied decimal places of a Decimal field written without decimal point.
+	Scale int `json:"scale,omitempty"`
+}
+
+// Layout represents the fields of the records of a fixed-width file.
+type Layout struct {
+	Fields []Field `json:"fields"`
+}
+
+// ParseLayout returns the layout defined as json in raw.
+func ParseLayout(raw []byte) (Layout, error) {
+	var l Layout
+	if err := json.Unmarshal(raw, &l); err != nil {
+		return Layout{}, fmt.Errorf("%w: %v", errInvalidLayout, err)
+	}
+	if err := l.validate(); err != nil {
+		return Layout{}, err
+	}
+	return l, nil
+}
+
+// Columns returns the names of the fields in order.
+func (l Layout) Columns() []string {
+	columns := make([]string, 0, len(l.Fields))
+	for _, f := range l.Fields {
+		columns = append(columns, f.Name)
+	}
+	return columns
+}
+
+func (l Layout) validate() error {
+	if len(l.Fields) == 0 {
+		return fmt.Errorf("%w: no fields", errInvalidLayout)
+	}
+
+	names := make(map[string]bool, len(l.Fields))
+	for i, f := range l.Fields {
+		name := strings.ToLower(strings.TrimSpace(f.Name))
+		switch {
+		case name == "":
+			return fmt.Errorf("%w: field %d has no name", errInvalidLayout, i)
+		case names[name]:
+			return fmt.Errorf("%w: field %s is repeated", errInvalidLayout, f.Name)
+		case f.Start < 1 || f.Length < 1:
+			return fmt.Errorf("%w: field %s must have a start and length of at least 1", errInvalidLayout, f.Name)
+		case f.Scale < 0 || f.Scale > 0 && f.Type != Decimal:
+			return fmt.Errorf("%w: field %s can't have a scale of %d", errInvalidLayout, f.Name, f.Scale)
+		}
+		switch f.Type {
+		case "", String, Integer, Decimal:
+		default:
+			return fmt.Errorf("%w: field %s has unknown type %q", errInvalidLayout, f.Name, f.Type)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// slice returns the text of the field in a record, which is blank past the end of short records.
+func (f Field) slice(rec []rune) string {
+	start, end := f.Start-1, f.Start-1+f.Length
+	if start >= len(rec) {
+		return ""
+	}
+	if end > len(rec) {
+		end = len(rec)
+	}
+	return string(rec[start:end])
+}
+
+// parse returns the value of the field from its text, trimmed of padding. Blank numbers are empty.
+func (f Field) parse(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if f.Type == "" || f.Type == String || text == "" {
+		return text, nil
+	}
+
+	if !number.MatchString(text) {
+		return "", fmt.Errorf("%w %q in field %s", errInvalidNumber, text, f.Name)
+	}
+	n, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return "", fmt.Errorf("%w %q in field %s", errInvalidNumber, text, f.Name)
+	}
+	if f.Type == Integer {
+		if !n.IsInt() {
+			return "", fmt.Errorf("%w %q in field %s: not an integer", errInvalidNumber, text, f.Name)
+		}
+		return n.Num().String(), nil
+	}
+
+	decimals := 0
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		decimals = len(text) - i - 1
+	} else if f.Scale > 0 {
+		n.Quo(n, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(f.Scale)), nil)))
+		decimals = f.Scale
+	}
+	return n.FloatString(decimals), nil
+}
@@ -0,0 +1,33 @@
+package fixedwidthreader
+
+import (
+	"context"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Name is the name of the fixed-width parser.
+const Name = "fixed-width"
+
+// Extensions are the extensions of fixed-width files.
+var Extensions = []string{".dat", ".fwf"}
+
+// Parser reads fixed-width files split by a layout.
+type Parser struct {
+	layout  Layout
+	mapping mapping.Mapping
+}
+
+// NewParser returns a fixed-width parser.
+func NewParser(layout Layout, m mapping.Mapping) *Parser {
+	return &Parser{layout: layout, mapping: m}
+}
+
+// Parse streams the records of a fixed-width file to sink.
+func (p *Parser) Parse(ctx context.Context, in parser.Input, sink *record.Sink, logger *zap.SugaredLogger) (string, error) {
+	logger.Info("Step 4 - Reading file as fixed-width")
+	return "", Read(ctx, in.Reader, p.layout, p.mapping, sink, logger)
+}
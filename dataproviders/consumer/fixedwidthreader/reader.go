@@ -0,0 +1,86 @@
+package fixedwidthreader
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Read streams the records of the fixed-width file in r, split by layout, to sink. Fields are matched to filedata
+// by their name in the layout.
+func Read(ctx context.Context, r io.Reader, layout Layout, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	if err := layout.validate(); err != nil {
+		return record.Unreadable(err)
+	}
+	binding, err := m.Bind(layout.Columns())
+	if err != nil {
+		return record.Unreadable(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	decode := func(row record.Row) (*domain.FileData, error) {
+		return binding.FileData(row.Fields)
+	}
+	return sink.Consume(ctx, ProcessFixedWidth(ctx, r, layout, logger), decode, logger)
+}
+
+// ProcessFixedWidth returns a channel for reading the records of a fixed-width file until ctx is done. Blank lines
+// are skipped. Records with fields that can't be parsed carry their error, and errors that stop the reading are
+// wrapped with record.Unreadable.
+func ProcessFixedWidth(ctx context.Context, rc io.Reader, layout Layout, logger *zap.SugaredLogger) (ch chan record.Row) {
+	ch = make(chan record.Row)
+
+	go func() {
+		defer close(ch)
+		br := bufio.NewReader(rc)
+
+		for line := 1; ; line++ {
+			text, err := br.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				logger.Errorf("Error reading fixed-width file: %v", err)
+				record.Send(ctx, ch, record.Row{Line: line, Err: record.Unreadable(err)})
+				return
+			}
+			if line == 1 && text == "" && err != nil {
+				record.Send(ctx, ch, record.Row{Line: line, Err: record.Unreadable(record.ErrEmptyFile)})
+				return
+			}
+
+			text = strings.TrimRight(text, "\r\n")
+			if line == 1 {
+				text = strings.TrimPrefix(text, "\ufeff")
+			}
+			if strings.TrimSpace(text) != "" && !record.Send(ctx, ch, toRow(line, text, layout)) {
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return
+}
+
+// ---------- Helpers ------------ //
+
+func toRow(line int, text string, layout Layout) record.Row {
+	rec := []rune(text)
+	row := record.Row{Line: line, Raw: text, Fields: make([]string, 0, len(layout.Fields))}
+	for _, f := range layout.Fields {
+		value, err := f.parse(f.slice(rec))
+		if err != nil && row.Err == nil {
+			row.Err = err
+		}
+		row.Fields = append(row.Fields, value)
+	}
+	return row
+}
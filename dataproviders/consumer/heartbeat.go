@@ -0,0 +1,64 @@
+package consumer
+
+import (
+	"context"
+	"time"
+)
+
+// releaseTimeout bounds the call made to release a message during shutdown.
+const releaseTimeout = 5 * time.Second
+
+// heartbeat periodically extends the visibility timeout of every in-flight message of a queue until the source is closed.
+func (s *SQSSource) heartbeat(ctx context.Context, q *queue) {
+	interval := s.heartbeatInterval
+	if interval <= 0 {
+		interval = time.Duration(q.client.VisibilityTimeout()) * time.Second / 2
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, m := range q.inflightMessages() {
+				if err := q.client.ChangeMessageVisibility(ctx, m.sqsMessage, q.client.VisibilityTimeout()); err != nil {
+					m.log.Errorf("Error extending visibility timeout: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// releaseInflight makes every in-flight message visible again and returns how many were released.
+func (s *SQSSource) releaseInflight() int {
+	released := 0
+	for _, q := range s.queues {
+		for _, m := range q.inflightMessages() {
+			q.untrack(m)
+			s.release(m)
+			released++
+		}
+	}
+	return released
+}
+
+// release resets the visibility timeout of a message to zero. It doesn't depend on the work context
+// because it runs precisely when that context has been cancelled.
+func (s *SQSSource) release(m *message) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	if err := m.queue.client.ChangeMessageVisibility(ctx, m.sqsMessage, 0); err != nil {
+		m.log.Errorf("Error releasing message: %v", err)
+		return
+	}
+	m.log.Infof("Message released for redelivery")
+}
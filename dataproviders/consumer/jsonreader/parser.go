@@ -0,0 +1,36 @@
+package jsonreader
+
+import (
+	"context"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Name is the name of the json parser.
+const Name = "json"
+
+var (
+	// Extensions are the extensions of json array and json lines files.
+	Extensions = []string{".json", ".jsonl", ".ndjson"}
+	// ContentTypes are the media types of json array and json lines files.
+	ContentTypes = []string{"application/json", "application/jsonl", "application/x-ndjson", "application/x-jsonlines"}
+)
+
+// Parser reads json array and json lines files.
+type Parser struct {
+	mapping mapping.Mapping
+}
+
+// NewParser returns a json parser.
+func NewParser(m mapping.Mapping) *Parser {
+	return &Parser{mapping: m}
+}
+
+// Parse streams the records of a json file to sink.
+func (p *Parser) Parse(ctx context.Context, in parser.Input, sink *record.Sink, logger *zap.SugaredLogger) (string, error) {
+	logger.Info("Step 4 - Reading file as JSON")
+	return "", Read(ctx, in.Reader, p.mapping, sink, logger)
+}
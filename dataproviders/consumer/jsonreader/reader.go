@@ -0,0 +1,176 @@
+package jsonreader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+var (
+	errInvalidJSON = errors.New("invalid json")
+	errNotObject   = errors.New("record is not a json object")
+)
+
+// Read streams the records of a json file in r to sink. The file is either a json array of objects or
+// json lines, one object per line. Object keys are matched to filedata by m.
+func Read(ctx context.Context, r io.Reader, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	decode := func(row record.Row) (*domain.FileData, error) {
+		return toFileData(row.Raw, m)
+	}
+	return sink.Consume(ctx, ProcessJSON(ctx, r, logger), decode, logger)
+}
+
+// ProcessJSON returns a channel for reading the records of a json array or json lines file until ctx is done.
+// The line of the records of an array is their position in the array. Malformed records carry their error,
+// and errors that stop the reading are wrapped with record.Unreadable.
+func ProcessJSON(ctx context.Context, rc io.Reader, logger *zap.SugaredLogger) (ch chan record.Row) {
+	ch = make(chan record.Row)
+
+	go func() {
+		defer close(ch)
+		br := bufio.NewReader(rc)
+		skipBOM(br)
+
+		first, skipped, err := peek(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = record.ErrEmptyFile
+			}
+			logger.Errorf("Error reading JSON: %v", err)
+			record.Send(ctx, ch, record.Row{Line: 1, Err: record.Unreadable(err)})
+			return
+		}
+
+		if first == '[' {
+			readArray(ctx, br, ch)
+			return
+		}
+		readLines(ctx, br, 1+skipped, ch)
+	}()
+	return
+}
+
+// ---------- Helpers ------------ //
+
+func readArray(ctx context.Context, r io.Reader, ch chan record.Row) {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		record.Send(ctx, ch, record.Row{Line: 1, Err: record.Unreadable(err)})
+		return
+	}
+
+	for i := 1; dec.More(); i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			// the decoder can't resume after a syntax error.
+			record.Send(ctx, ch, record.Row{Line: i, Err: record.Unreadable(err)})
+			return
+		}
+		if !record.Send(ctx, ch, record.Row{Line: i, Raw: compact(raw)}) {
+			return
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		record.Send(ctx, ch, record.Row{Err: record.Unreadable(err)})
+	}
+}
+
+func readLines(ctx context.Context, r *bufio.Reader, first int, ch chan record.Row) {
+	for line := first; ; line++ {
+		text, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(text)) > 0 {
+			row := record.Row{Line: line, Raw: string(bytes.TrimSpace(text))}
+			if !json.Valid(text) {
+				row.Err = errInvalidJSON
+			}
+			if !record.Send(ctx, ch, row) {
+				return
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				record.Send(ctx, ch, record.Row{Line: line, Err: record.Unreadable(err)})
+			}
+			return
+		}
+	}
+}
+
+// toFileData maps the keys of a json object to filedata.
+func toFileData(raw string, m mapping.Mapping) (*domain.FileData, error) {
+	object := make(map[string]json.RawMessage)
+	if err := json.Unmarshal([]byte(raw), &object); err != nil || object == nil {
+		return nil, errNotObject
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, toValue(object[name]))
+	}
+	return m.FileData(names, values)
+}
+
+// toValue returns the text of a json value: strings unquoted, null empty and any other value as json.
+func toValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return compact(raw)
+}
+
+func compact(raw []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(bytes.TrimSpace(raw))
+	}
+	return buf.String()
+}
+
+func skipBOM(r *bufio.Reader) {
+	if b, err := r.Peek(3); err == nil && bytes.Equal(b, []byte{0xef, 0xbb, 0xbf}) {
+		_, _ = r.Discard(3)
+	}
+}
+
+// peek returns the first byte of r that isn't a space, and the number of lines skipped to reach it.
+func peek(r *bufio.Reader) (byte, int, error) {
+	lines := 0
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, lines, err
+		}
+		switch b[0] {
+		case '\n':
+			lines++
+			fallthrough
+		case ' ', '\t', '\r':
+			_, _ = r.Discard(1)
+		default:
+			return b[0], lines, nil
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package mapping
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"strconv"
+	"strings"
+)
+
+// Fields of domain.FileData that can be mapped from a file.
+const (
+	FieldID      = "id"
+	FieldMessage = "message"
+	FieldOwner   = "owner"
+)
+
+// ExtraMode is what happens with the columns of a file that aren't mapped to a field.
+type ExtraMode string
+
+const (
+	// Ignore drops the extra columns.
+	Ignore ExtraMode = "ignore"
+	// Keep stores the extra columns with the row.
+	Keep ExtraMode = "keep"
+)
+
+var (
+	errMissingColumn = errors.New("missing required column")
+	errInvalidValue  = errors.New("invalid value")
+)
+
+// Column describes how a field is read from the columns of a file.
+type Column struct {
+	// Field is the name of the domain.FileData field.
+	Field string `json:"field"`
+	// Aliases are other header names accepted for the field.
+	Aliases []string `json:"aliases"`
+	// Required columns must be in the header, unless a default is set.
+	Required bool `json:"required"`
+	// Default is the value used when the column is missing or empty.
+	Default string `json:"default"`
+}
+
+// Mapping represents how the columns of a file are matched to domain.FileData.
+type Mapping struct {
+	Columns []Column  `json:"columns"`
+	Extra   ExtraMode `json:"extra"`
+}
+
+// Default returns the mapping of files with the id, message and owner columns.
+func Default() Mapping {
+	return Mapping{
+		Columns: []Column{
+			{Field: FieldID, Required: true},
+			{Field: FieldMessage, Required: true},
+			{Field: FieldOwner, Required: true},
+		},
+		Extra: Ignore,
+	}
+}
+
+// Parse returns the mapping described as json in raw, or the default mapping when raw is empty.
+func Parse(raw string) (Mapping, error) {
+	if raw == "" {
+		return Default(), nil
+	}
+
+	m := Mapping{}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return Mapping{}, fmt.Errorf("invalid mapping: %w", err)
+	}
+	if m.Extra == "" {
+		m.Extra = Ignore
+	}
+	return m, m.validate()
+}
+
+func (m Mapping) validate() error {
+	if m.Extra != Ignore && m.Extra != Keep {
+		return fmt.Errorf("unknown extra columns mode %q", m.Extra)
+	}
+	seen := make(map[string]bool)
+	for _, c := range m.Columns {
+		switch c.Field {
+		case FieldID, FieldMessage, FieldOwner:
+		default:
+			return fmt.Errorf("unknown field %q", c.Field)
+		}
+		if seen[c.Field] {
+			return fmt.Errorf("field %q mapped twice", c.Field)
+		}
+		seen[c.Field] = true
+	}
+	return nil
+}
+
+// Fields returns the fields of the mapping in order.
+func (m Mapping) Fields() []string {
+	fields := make([]string, 0, len(m.Columns))
+	for _, c := range m.Columns {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+// Binding represents a mapping resolved against the header of a file.
+type Binding struct {
+	columns []Column
+	// index is the position of each column in the header, -1 when it's missing.
+	index []int
+	extra map[int]string
+}
+
+// Bind resolves the columns of the mapping in header. Header names are matched ignoring case and surrounding spaces.
+func (m Mapping) Bind(header []string) (*Binding, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = normalize(name)
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	b := &Binding{
+		columns: m.Columns,
+		index:   make([]int, len(m.Columns)),
+		extra:   make(map[int]string),
+	}
+	mapped := make(map[int]bool)
+	for i, c := range m.Columns {
+		b.index[i] = -1
+		for _, name := range append([]string{c.Field}, c.Aliases...) {
+			if pos, ok := positions[normalize(name)]; ok {
+				b.index[i] = pos
+				mapped[pos] = true
+				break
+			}
+		}
+		if b.index[i] < 0 && c.Required && c.Default == "" {
+			return nil, fmt.Errorf("%w: %s", errMissingColumn, c.Field)
+		}
+	}
+
+	if m.Extra == Keep {
+		for i, name := range header {
+			if !mapped[i] {
+				b.extra[i] = strings.TrimSpace(name)
+			}
+		}
+	}
+	return b, nil
+}
+
+// FileData returns the filedata of a row of the file.
+func (b *Binding) FileData(fields []string) (*domain.FileData, error) {
+	filedata := &domain.FileData{}
+	for i, c := range b.columns {
+		value := c.Default
+		if pos := b.index[i]; pos >= 0 && pos < len(fields) && strings.TrimSpace(fields[pos]) != "" {
+			value = fields[pos]
+		}
+		switch c.Field {
+		case FieldID:
+			if value == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w for %s: %q", errInvalidValue, c.Field, value)
+			}
+			filedata.ID = &id
+		case FieldMessage:
+			filedata.Message = value
+		case FieldOwner:
+			filedata.Owner = value
+		}
+	}
+
+	if len(b.extra) > 0 {
+		filedata.Extra = make(map[string]string, len(b.extra))
+		for pos, name := range b.extra {
+			if pos < len(fields) {
+				filedata.Extra[name] = fields[pos]
+			}
+		}
+	}
+	return filedata, nil
+}
+
+// FileData returns the filedata of a record whose values are named by names, like the objects of json files.
+// A missing required column is an error of the record rather than of the file.
+func (m Mapping) FileData(names, values []string) (*domain.FileData, error) {
+	b, err := m.Bind(names)
+	if err != nil {
+		return nil, err
+	}
+	return b.FileData(values)
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
@@ -0,0 +1,108 @@
+package mapping
+
+import (
+	"errors"
+	"reflect"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"testing"
+)
+
+func TestMappingBind(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name    string
+		mapping Mapping
+		header  []string
+		row     []string
+		want    *domain.FileData
+		wantErr error
+	}{
+		{
+			name:    "columns in any order and case",
+			mapping: Default(),
+			header:  []string{"\ufeffOwner", " MESSAGE ", "id"},
+			row:     []string{"ventas", "hola", "7"},
+			want:    &domain.FileData{ID: id(7), Message: "hola", Owner: "ventas"},
+		},
+		{
+			name: "aliases",
+			mapping: Mapping{Columns: []Column{
+				{Field: FieldID, Aliases: []string{"codigo", "code"}, Required: true},
+				{Field: FieldMessage, Aliases: []string{"texto"}},
+				{Field: FieldOwner},
+			}},
+			header: []string{"code", "texto", "owner"},
+			row:    []string{"1", "hola", "soporte"},
+			want:   &domain.FileData{ID: id(1), Message: "hola", Owner: "soporte"},
+		},
+		{
+			name: "the field name wins over its aliases",
+			mapping: Mapping{Columns: []Column{
+				{Field: FieldMessage, Aliases: []string{"texto"}},
+			}},
+			header: []string{"texto", "message"},
+			row:    []string{"alias", "field"},
+			want:   &domain.FileData{Message: "field"},
+		},
+		{
+			name: "defaults for missing and empty columns",
+			mapping: Mapping{Columns: []Column{
+				{Field: FieldID},
+				{Field: FieldMessage, Default: "sin mensaje"},
+				{Field: FieldOwner, Required: true, Default: "nadie"},
+			}},
+			header: []string{"id", "message"},
+			row:    []string{"3", "  "},
+			want:   &domain.FileData{ID: id(3), Message: "sin mensaje", Owner: "nadie"},
+		},
+		{
+			name:    "missing required column",
+			mapping: Default(),
+			header:  []string{"id", "message"},
+			wantErr: errMissingColumn,
+		},
+		{
+			name:    "invalid id",
+			mapping: Default(),
+			header:  []string{"id", "message", "owner"},
+			row:     []string{"uno", "hola", "ventas"},
+			wantErr: errInvalidValue,
+		},
+		{
+			name:    "extra columns ignored",
+			mapping: Default(),
+			header:  []string{"id", "message", "owner", "region"},
+			row:     []string{"1", "hola", "ventas", "norte"},
+			want:    &domain.FileData{ID: id(1), Message: "hola", Owner: "ventas"},
+		},
+		{
+			name: "extra columns kept",
+			mapping: Mapping{
+				Columns: Default().Columns,
+				Extra:   Keep,
+			},
+			header: []string{"id", " Region ", "message", "owner", "zona"},
+			row:    []string{"1", "norte", "hola", "ventas"},
+			want: &domain.FileData{ID: id(1), Message: "hola", Owner: "ventas", Extra: map[string]string{
+				"Region": "norte",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.mapping.Bind(tt.header)
+			if err == nil {
+				var got *domain.FileData
+				got, err = b.FileData(tt.row)
+				if err == nil && !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("FileData() = %+v, want %+v", got, tt.want)
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package consumer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.uber.org/zap"
+)
+
+// message tracks the outcome of every record fanned out from a single SQS message.
+type message struct {
+	sqsMessage *sqs.Message
+	queue      *queue
+	log        *zap.SugaredLogger
+	mu         sync.Mutex
+	pending    int
+	errs       []error
+}
+
+// expect sets the number of records the message waits for.
+func (m *message) expect(records int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = records
+}
+
+// done records the outcome of one record. It reports whether every record of the
+// message has finished and how many of them failed.
+func (m *message) done(err error) (finished bool, failed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending--
+	if err != nil {
+		m.errs = append(m.errs, err)
+	}
+	return m.pending == 0, len(m.errs)
+}
+
+// failure returns the combined error of the failed records. It is permanent only
+// when every failure is permanent.
+func (m *message) failure() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.errs) == 0 {
+		return nil
+	}
+
+	msgs := make([]string, 0, len(m.errs))
+	perm := true
+	for _, err := range m.errs {
+		msgs = append(msgs, err.Error())
+		perm = perm && isPermanent(err)
+	}
+
+	err := errorList(strings.Join(msgs, "; "))
+	if perm {
+		return permanent(err)
+	}
+	return err
+}
+
+type errorList string
+
+func (e errorList) Error() string {
+	return string(e)
+}
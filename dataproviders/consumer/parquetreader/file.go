@@ -0,0 +1,42 @@
+package parquetreader
+
+import (
+	"errors"
+	"io"
+
+	"github.com/xitongsys/parquet-go/source"
+)
+
+var errReadOnly = errors.New("parquet file is read only")
+
+// file adapts an io.ReaderAt to the source.ParquetFile read by the parquet reader.
+// Every Open returns an independent cursor, so columns can be read concurrently.
+type file struct {
+	*io.SectionReader
+	r    io.ReaderAt
+	size int64
+}
+
+func newFile(r io.ReaderAt, size int64) *file {
+	return &file{
+		SectionReader: io.NewSectionReader(r, 0, size),
+		r:             r,
+		size:          size,
+	}
+}
+
+func (f *file) Open(string) (source.ParquetFile, error) {
+	return newFile(f.r, f.size), nil
+}
+
+func (f *file) Create(string) (source.ParquetFile, error) {
+	return nil, errReadOnly
+}
+
+func (f *file) Write([]byte) (int, error) {
+	return 0, errReadOnly
+}
+
+func (f *file) Close() error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package parquetreader
+
+import (
+	"context"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Name is the name of the parquet parser.
+const Name = "parquet"
+
+var (
+	// Extensions are the extensions of parquet files.
+	Extensions = []string{".parquet"}
+	// ContentTypes are the media types of parquet files.
+	ContentTypes = []string{"application/vnd.apache.parquet", "application/x-parquet"}
+)
+
+// Parser reads parquet files. Compressed files are spooled, since the footer is read first.
+type Parser struct {
+	mapping mapping.Mapping
+}
+
+// NewParser returns a parquet parser.
+func NewParser(m mapping.Mapping) *Parser {
+	return &Parser{mapping: m}
+}
+
+// Parse streams the rows of a parquet file to sink.
+func (p *Parser) Parse(ctx context.Context, in parser.Input, sink *record.Sink, logger *zap.SugaredLogger) (string, error) {
+	logger.Info("Step 4 - Reading file as Parquet")
+	r, size, err := in.RandomAccess()
+	if err != nil {
+		return "", err
+	}
+	return "", Read(ctx, r, size, p.mapping, sink, logger)
+}
@@ -0,0 +1,133 @@
+package parquetreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/reader"
+	"go.uber.org/zap"
+)
+
+// rowsPerRead is the number of rows read at once from a row group.
+const rowsPerRead = 1000
+
+// Read streams the rows of a parquet file to sink, one row group at a time. Columns are matched to filedata by
+// name, nested columns by their dotted path, and null values are read as empty.
+func Read(ctx context.Context, r io.ReaderAt, size int64, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	pr, err := reader.NewParquetReader(newFile(r, size), nil, 1)
+	if err != nil {
+		return record.Unreadable(err)
+	}
+	defer pr.ReadStop()
+
+	columns := pr.SchemaHandler.ValueColumns
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, columnName(pr.SchemaHandler.InPathToExPath[column]))
+	}
+	binding, err := m.Bind(header)
+	if err != nil {
+		return record.Unreadable(err)
+	}
+
+	decode := func(row record.Row) (*domain.FileData, error) {
+		return binding.FileData(row.Fields)
+	}
+
+	line := 0
+	for i, rowGroup := range pr.Footer.RowGroups {
+		logger.Debugf("Reading row group %d with %d row(s)", i, rowGroup.NumRows)
+		for read := int64(0); read < rowGroup.NumRows; read += rowsPerRead {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+
+			rows, err := readRows(pr, columns, min(rowsPerRead, rowGroup.NumRows-read))
+			if err != nil {
+				return record.Unreadable(err)
+			}
+			for _, fields := range rows {
+				line++
+				if err = sink.Handle(ctx, record.Row{Line: line, Fields: fields, Raw: strings.Join(fields, ",")}, decode, logger); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return sink.Flush(ctx)
+}
+
+// ---------- Helpers ------------ //
+
+// readRows reads num rows of every column. Repeated columns join their values with commas.
+func readRows(pr *reader.ParquetReader, columns []string, num int64) ([][]string, error) {
+	rows := make([][]string, num)
+	for i := range rows {
+		rows[i] = make([]string, len(columns))
+	}
+
+	for c, column := range columns {
+		values, rls, _, err := pr.ReadColumnByPath(column, num)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+
+		row := -1
+		for i, v := range values {
+			if rls[i] == 0 {
+				row++
+			}
+			if row < 0 || row >= len(rows) || v == nil {
+				continue
+			}
+			if rows[row][c] != "" {
+				rows[row][c] += ","
+			}
+			rows[row][c] += toText(v)
+		}
+	}
+	return rows, nil
+}
+
+// columnName returns the dotted path of a column without the schema root.
+func columnName(exPath string) string {
+	path := strings.Split(exPath, common.PAR_GO_PATH_DELIMITER)
+	if len(path) > 1 {
+		path = path[1:]
+	}
+	return strings.Join(path, ".")
+}
+
+func toText(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case bool:
+		return strconv.FormatBool(t)
+	case int32:
+		return strconv.FormatInt(int64(t), 10)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func min(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Parser reads the rows of files of a format.
+type Parser interface {
+	// Parse streams the rows of in to sink. It returns the dialect the file was read with, empty when the format
+	// has none. Errors about the contents of the file are wrapped with record.Unreadable.
+	Parse(ctx context.Context, in Input, sink *record.Sink, logger *zap.SugaredLogger) (dialect string, err error)
+}
+
+// Input represents a file handed to a parser.
+type Input struct {
+	Bucket string
+	Key    string
+	// Name is the name of the contents, the key without its compression extension or the name of an archive entry.
+	Name        string
+	ContentType string
+	// Metadata is the user metadata of the object.
+	Metadata map[string]string
+	// Reader streams the contents of the file.
+	Reader io.Reader
+	// ReaderAt reads the contents of Size bytes at random. It is nil when the contents are a stream, see Spool.
+	ReaderAt io.ReaderAt
+	Size     int64
+	// Spool copies the stream to a local file for parsers that need random access. The file is deleted once the
+	// parser returns.
+	Spool func() (io.ReaderAt, int64, error)
+}
+
+// RandomAccess returns a reader of the contents at random, spooling them when they are a stream.
+func (in Input) RandomAccess() (io.ReaderAt, int64, error) {
+	if in.ReaderAt != nil {
+		return in.ReaderAt, in.Size, nil
+	}
+	return in.Spool()
+}
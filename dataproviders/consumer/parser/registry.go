@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	errUnknownParser = errors.New("unknown parser")
+	errNoParser      = errors.New("no parser for file")
+)
+
+// Registration represents a parser and the files it reads by default.
+type Registration struct {
+	Name   string
+	Parser Parser
+	// Extensions are the extensions of the names of the files, with the leading dot.
+	Extensions []string
+	// ContentTypes are the media types of the files.
+	ContentTypes []string
+}
+
+// Route sends the files under a key prefix to a parser. An empty Bucket matches every bucket.
+type Route struct {
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix"`
+	Parser string `json:"parser"`
+}
+
+// Registry chooses the parser of a file by, in order, an object tag, the key prefix, the extension of its name and
+// its Content-Type, falling back to a default parser.
+type Registry struct {
+	tagKey       string
+	parsers      map[string]Parser
+	extensions   map[string]string
+	contentTypes map[string]string
+	routes       []Route
+	fallback     string
+}
+
+// NewRegistry returns an empty registry. The parser of a file can be named by the object tag tagKey, which is
+// ignored when empty.
+func NewRegistry(tagKey string) *Registry {
+	return &Registry{
+		tagKey:       tagKey,
+		parsers:      make(map[string]Parser),
+		extensions:   make(map[string]string),
+		contentTypes: make(map[string]string),
+	}
+}
+
+// Register adds a parser. Extensions and content types already taken are given to the new parser.
+func (r *Registry) Register(reg Registration) error {
+	if reg.Name == "" || reg.Parser == nil {
+		return errors.New("a parser needs a name")
+	}
+	if _, ok := r.parsers[reg.Name]; ok {
+		return fmt.Errorf("parser %s is already registered", reg.Name)
+	}
+
+	r.parsers[reg.Name] = reg.Parser
+	for _, ext := range reg.Extensions {
+		r.extensions[strings.ToLower(ext)] = reg.Name
+	}
+	for _, ct := range reg.ContentTypes {
+		r.contentTypes[strings.ToLower(ct)] = reg.Name
+	}
+	return nil
+}
+
+// Route sends the files under a key prefix to a registered parser.
+func (r *Registry) Route(route Route) error {
+	if _, ok := r.parsers[route.Parser]; !ok {
+		return fmt.Errorf("%w %q in route of prefix %q", errUnknownParser, route.Parser, route.Prefix)
+	}
+	r.routes = append(r.routes, route)
+	// longest prefixes first, and bucket specific routes before the ones of every bucket.
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		if len(r.routes[i].Prefix) != len(r.routes[j].Prefix) {
+			return len(r.routes[i].Prefix) > len(r.routes[j].Prefix)
+		}
+		return r.routes[i].Bucket != "" && r.routes[j].Bucket == ""
+	})
+	return nil
+}
+
+// SetDefault sets the registered parser of the files no other rule chooses.
+func (r *Registry) SetDefault(name string) error {
+	if _, ok := r.parsers[name]; !ok {
+		return fmt.Errorf("%w %q", errUnknownParser, name)
+	}
+	r.fallback = name
+	return nil
+}
+
+// TagKey returns the object tag naming the parser of a file, empty when tags aren't used.
+func (r *Registry) TagKey() string {
+	return r.tagKey
+}
+
+// Knows reports whether a parser is registered for the extension of name.
+func (r *Registry) Knows(name string) bool {
+	_, ok := r.extensions[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// Resolve returns the name and the parser of a file.
+func (r *Registry) Resolve(in Input, tags map[string]string) (string, Parser, error) {
+	name := r.choose(in, tags)
+	if name == "" {
+		return "", nil, fmt.Errorf("%w %s", errNoParser, in.Name)
+	}
+	p, ok := r.parsers[name]
+	if !ok {
+		return "", nil, fmt.Errorf("%w %q", errUnknownParser, name)
+	}
+	return name, p, nil
+}
+
+func (r *Registry) choose(in Input, tags map[string]string) string {
+	if name := tags[r.tagKey]; r.tagKey != "" && name != "" {
+		return name
+	}
+	for _, route := range r.routes {
+		if (route.Bucket == "" || route.Bucket == in.Bucket) && strings.HasPrefix(in.Key, route.Prefix) {
+			return route.Parser
+		}
+	}
+	if name, ok := r.extensions[strings.ToLower(path.Ext(in.Name))]; ok {
+		return name
+	}
+	if mediaType, _, err := mime.ParseMediaType(in.ContentType); err == nil {
+		if name, ok := r.contentTypes[mediaType]; ok {
+			return name
+		}
+	}
+	return r.fallback
+}
+
+// ParseRoutes returns the routes listed as a json array in raw, empty when raw is empty.
+func ParseRoutes(raw string) ([]Route, error) {
+	routes := make([]Route, 0)
+	if raw == "" {
+		return routes, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &routes); err != nil {
+		return nil, fmt.Errorf("parser routes must be a json array: %w", err)
+	}
+	return routes, nil
+}
@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+
+	"service-worker-sqs-s3-postgres/core/domain"
+)
+
+// pool runs the download and insert stages of the pipeline with a bounded number of workers.
+// The stages are connected by bounded queues so a slow stage applies backpressure to the poller.
+type pool struct {
+	downloaders int
+	inserters   int
+	downloads   chan *s3Event
+	inserts     chan *s3Event
+	wg          sync.WaitGroup
+	mu          sync.RWMutex
+	stopped     bool
+}
+
+// newPool returns a pool with the given number of workers per stage whose queues hold up to queueSize records each.
+func newPool(downloaders, inserters, queueSize int) *pool {
+	return &pool{
+		downloaders: atLeastOne(downloaders),
+		inserters:   atLeastOne(inserters),
+		downloads:   make(chan *s3Event, atLeastOne(queueSize)),
+		inserts:     make(chan *s3Event, atLeastOne(queueSize)),
+	}
+}
+
+// start launches the workers of every stage.
+func (p *pool) start(ctx context.Context, s *SQSSource, out chan *domain.Event) {
+	downloadWG := &sync.WaitGroup{}
+	for i := 0; i < p.downloaders; i++ {
+		downloadWG.Add(1)
+		go func() {
+			defer downloadWG.Done()
+			for s3Event := range p.downloads {
+				if s.downloadRecord(ctx, s3Event) {
+					p.inserts <- s3Event
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < p.inserters; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for s3Event := range p.inserts {
+				s.persistRecord(ctx, s3Event, out)
+			}
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		downloadWG.Wait()
+		close(p.inserts)
+	}()
+}
+
+// submit queues a record for download, blocking while the queue is full. It reports false once the pool is stopped.
+func (p *pool) submit(s3Event *s3Event) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return false
+	}
+	p.downloads <- s3Event
+	return true
+}
+
+// stop closes the intake of the pool; the workers finish the queued records and exit.
+func (p *pool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.stopped {
+		p.stopped = true
+		close(p.downloads)
+	}
+}
+
+// wait blocks until every worker has exited.
+func (p *pool) wait() {
+	p.wg.Wait()
+}
+
+// share returns the workers of the i-th of parts queues when n workers are spread between them, the first queues
+// taking the remainder.
+func share(n, parts, i int) int {
+	workers := n / parts
+	if i < n%parts {
+		workers++
+	}
+	return workers
+}
+
+func atLeastOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.uber.org/zap"
+	"service-worker-sqs-s3-postgres/dataproviders/awssqs"
+	rfiledata "service-worker-sqs-s3-postgres/dataproviders/postgres/repository/filedata"
+)
+
+// DefaultPipeline is the pipeline that stores rows in the filedata table.
+const DefaultPipeline = "filedata"
+
+// Queue represents an SQS queue consumed by the source.
+type Queue struct {
+	// Client polls the queue.
+	Client *awssqs.ClientSQS
+	// DeadLetter receives the messages that failed permanently. When nil they are kept in the queue.
+	DeadLetter *awssqs.ClientSQS
+	// Buckets restricts the buckets accepted from the queue. Empty accepts every bucket.
+	Buckets []string
+	// Pipeline is the name of the pipeline that stores the rows. Empty uses DefaultPipeline.
+	Pipeline string
+}
+
+// Pipeline represents where the rows of the files received by a queue are stored.
+type Pipeline struct {
+	FileData rfiledata.IFileDataRepository
+}
+
+// queue holds the runtime state of a consumed queue.
+type queue struct {
+	name       string
+	client     *awssqs.ClientSQS
+	deadLetter *awssqs.ClientSQS
+	buckets    map[string]bool
+	pipeline   Pipeline
+	pool       *pool
+	groups     *groups
+	log        *zap.SugaredLogger
+
+	mu       sync.Mutex
+	inflight map[string]*message
+}
+
+// newQueue validates the configuration of a queue and returns its runtime state.
+func newQueue(cfg Queue, pipelines map[string]Pipeline, p *pool, logger *zap.SugaredLogger) (*queue, error) {
+	if cfg.Client == nil {
+		return nil, fmt.Errorf("queue without client")
+	}
+
+	name := path.Base(cfg.Client.URL())
+	pipelineName := cfg.Pipeline
+	if pipelineName == "" {
+		pipelineName = DefaultPipeline
+	}
+	pipeline, ok := pipelines[pipelineName]
+	if !ok {
+		return nil, fmt.Errorf("queue %s: unknown pipeline %q", name, pipelineName)
+	}
+
+	buckets := make(map[string]bool, len(cfg.Buckets))
+	for _, b := range cfg.Buckets {
+		buckets[b] = true
+	}
+
+	return &queue{
+		name:       name,
+		client:     cfg.Client,
+		deadLetter: cfg.DeadLetter,
+		buckets:    buckets,
+		pipeline:   pipeline,
+		pool:       p,
+		groups:     newGroups(),
+		log:        logger.With("queue", name),
+		inflight:   make(map[string]*message),
+	}, nil
+}
+
+// allows reports whether the queue accepts files from bucket.
+func (q *queue) allows(bucket string) bool {
+	return len(q.buckets) == 0 || q.buckets[bucket]
+}
+
+// track registers a received message as in-flight so the heartbeat keeps it invisible.
+func (q *queue) track(m *message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.inflight[aws.StringValue(m.sqsMessage.MessageId)] = m
+}
+
+// untrack removes a message from the in-flight set once it has been handled. It reports whether the message was tracked.
+func (q *queue) untrack(m *message) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	id := aws.StringValue(m.sqsMessage.MessageId)
+	_, ok := q.inflight[id]
+	delete(q.inflight, id)
+	return ok
+}
+
+// inflightMessages returns a snapshot of the in-flight messages.
+func (q *queue) inflightMessages() []*message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := make([]*message, 0, len(q.inflight))
+	for _, m := range q.inflight {
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// newMessage returns the tracker of a message received from the queue.
+func (q *queue) newMessage(msg *sqs.Message) *message {
+	return &message{
+		sqsMessage: msg,
+		queue:      q,
+		log:        q.log.With("messageId", aws.StringValue(msg.MessageId)),
+	}
+}
@@ -0,0 +1,74 @@
+package record
+
+import (
+	"fmt"
+)
+
+// PolicyMode is what happens with the rows that can't be ingested.
+type PolicyMode string
+
+const (
+	// Fail rejects the whole file on the first bad row.
+	Fail PolicyMode = "fail"
+	// Skip drops bad rows, keeping them with the reason they were rejected.
+	Skip PolicyMode = "skip"
+)
+
+// ParsePolicyMode returns the policy mode with the given name.
+func ParsePolicyMode(name string) (PolicyMode, error) {
+	switch mode := PolicyMode(name); mode {
+	case Fail, Skip:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown reject policy %q", name)
+}
+
+// Policy represents how bad rows of a file are handled.
+type Policy struct {
+	Mode PolicyMode
+	// MaxRejected is the number of bad rows tolerated by Skip before the file fails. Zero means no limit.
+	MaxRejected int
+}
+
+// Report tallies the rows of a file under a policy.
+type Report struct {
+	policy   Policy
+	Accepted int
+	Rejected int
+	// Rejections are the rejected rows of the file with their reason, kept after they are handed to a Sink handler
+	// so they can be recorded again when the file fails and the chunks holding them are rolled back.
+	Rejections []Rejection
+}
+
+// NewReport returns an empty report for the given policy.
+func NewReport(policy Policy) *Report {
+	return &Report{
+		policy:     policy,
+		Rejections: make([]Rejection, 0),
+	}
+}
+
+// Accept counts a row as ingested.
+func (r *Report) Accept() {
+	r.Accepted++
+}
+
+// Reject counts and keeps a bad row. It returns an error when the policy fails the file.
+func (r *Report) Reject(row Row) error {
+	r.Rejected++
+	r.Rejections = append(r.Rejections, row.Rejection())
+
+	switch r.policy.Mode {
+	case Skip:
+	default:
+		if row.Sheet != "" {
+			return fmt.Errorf("%w: sheet %s line %d: %v", ErrRejectedFile, row.Sheet, row.Line, row.Err)
+		}
+		return fmt.Errorf("%w: line %d: %v", ErrRejectedFile, row.Line, row.Err)
+	}
+
+	if r.policy.MaxRejected > 0 && r.Rejected > r.policy.MaxRejected {
+		return fmt.Errorf("%d rows rejected, threshold is %d: %w", r.Rejected, r.policy.MaxRejected, ErrTooManyRejected)
+	}
+	return nil
+}
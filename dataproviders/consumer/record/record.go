@@ -0,0 +1,93 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Row represents a single row read from a file.
+type Row struct {
+	// Sheet is the sheet of workbooks the row belongs to.
+	Sheet string
+	// Line is the position of the row in the file, or in its sheet, starting at 1.
+	Line int
+	// Raw is the text of the row as read from the file.
+	Raw string
+	// Fields are the values of the row.
+	Fields []string
+	// Err is the reason the row can't be read. Errors wrapping ErrUnreadable abort the whole file.
+	Err error
+}
+
+// Rejection represents a row left out of the ingestion of a file.
+type Rejection struct {
+	Sheet  string
+	Line   int
+	Raw    string
+	Reason string
+}
+
+// Rejection returns the rejection of a bad row.
+func (r Row) Rejection() Rejection {
+	return Rejection{
+		Sheet:  r.Sheet,
+		Line:   r.Line,
+		Raw:    r.Raw,
+		Reason: r.Err.Error(),
+	}
+}
+
+func (r Row) position() string {
+	if r.Sheet != "" {
+		return fmt.Sprintf("%d of sheet %s", r.Line, r.Sheet)
+	}
+	return fmt.Sprintf("in line %d", r.Line)
+}
+
+var (
+	// ErrUnreadable means the file can't be read any further.
+	ErrUnreadable = errors.New("unreadable file")
+	// ErrEmptyFile means the file has no header.
+	ErrEmptyFile = errors.New("empty file")
+	// ErrRejectedFile means the policy rejected the whole file because of its bad rows.
+	ErrRejectedFile = errors.New("file rejected")
+	// ErrTooManyRejected means the rejected rows of a file exceeded the policy threshold.
+	ErrTooManyRejected = fmt.Errorf("%w: too many rejected rows", ErrRejectedFile)
+	// ErrInterrupted means the contents of the file stopped arriving, so reading it again may succeed.
+	ErrInterrupted = errors.New("file interrupted")
+)
+
+type unreadableError struct {
+	err error
+}
+
+func (e unreadableError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrUnreadable, e.err)
+}
+
+func (e unreadableError) Unwrap() error {
+	return e.err
+}
+
+func (e unreadableError) Is(target error) bool {
+	return target == ErrUnreadable
+}
+
+// Unreadable wraps an error that aborts the reading of a file.
+func Unreadable(err error) error {
+	return unreadableError{err: err}
+}
+
+// IsFileError reports whether err comes from the contents of the file rather than from handling its rows,
+// so reading it again would fail the same way. Interrupted files aren't file errors, even when they stopped the reading.
+func IsFileError(err error) bool {
+	if errors.Is(err, ErrInterrupted) {
+		return false
+	}
+	return errors.Is(err, ErrUnreadable) || errors.Is(err, ErrRejectedFile)
+}
+
+// Interrupted wraps an error of the stream a file is read from.
+func Interrupted(err error) error {
+	return fmt.Errorf("%w: %v", ErrInterrupted, err)
+}
@@ -0,0 +1,151 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"service-worker-sqs-s3-postgres/core/domain"
+
+	"go.uber.org/zap"
+)
+
+// DefaultChunkSize is the number of rows handed at once to a Handler when no size is given.
+const DefaultChunkSize = 1000
+
+// Handler persists a chunk of accepted rows along with the rows rejected since the previous chunk.
+type Handler func(ctx context.Context, chunk []*domain.FileData, rejected []Rejection) error
+
+// Decoder turns a row read from a file into filedata. A nil filedata without error skips the row, like a header.
+type Decoder func(row Row) (*domain.FileData, error)
+
+// Validator checks the rows of a file before they are persisted.
+type Validator interface {
+	Validate(data *domain.FileData) error
+}
+
+// Sink applies a policy to the rows of a file and hands them to a handler in chunks,
+// so only one chunk is held in memory at a time. Bad rows are handled by the policy, and reading a file fails
+// when the policy rejects it or the handler fails to persist a chunk.
+type Sink struct {
+	report   *Report
+	size     int
+	validate Validator
+	handle   Handler
+	chunk    []*domain.FileData
+	// flushed is the number of rejections of the report already handed to handle.
+	flushed int
+	// observe is told about every rejected row, whatever the policy.
+	observe func(Rejection) error
+}
+
+// NewSink returns a sink handing chunks of up to size rows to handle. Rows failing validate, if not nil, are rejected.
+func NewSink(policy Policy, size int, validate Validator, handle Handler) *Sink {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	return &Sink{
+		report:   NewReport(policy),
+		size:     size,
+		validate: validate,
+		handle:   handle,
+		chunk:    make([]*domain.FileData, 0, size),
+	}
+}
+
+// Report returns the tally of the rows received so far.
+func (s *Sink) Report() *Report {
+	return s.report
+}
+
+// Accept adds the filedata of a row to the current chunk, handing the chunk over once it is full.
+// Rows failing validation are rejected instead.
+func (s *Sink) Accept(ctx context.Context, row Row, data *domain.FileData) error {
+	if s.validate != nil {
+		if row.Err = s.validate.Validate(data); row.Err != nil {
+			return s.Reject(ctx, row)
+		}
+	}
+	s.report.Accept()
+	s.chunk = append(s.chunk, data)
+	if len(s.chunk) < s.size {
+		return nil
+	}
+	return s.Flush(ctx)
+}
+
+// Consume hands the rows of ch to the sink until ch is closed, then flushes the last chunk.
+// It stops at the first error, so the reader of ch must stop sending once ctx is canceled.
+func (s *Sink) Consume(ctx context.Context, ch <-chan Row, decode Decoder, logger *zap.SugaredLogger) error {
+	for row := range ch {
+		if err := s.Handle(ctx, row, decode, logger); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Flush(ctx)
+}
+
+// Handle decodes a row and accepts it, or rejects it when it is bad. Errors wrapping ErrUnreadable abort the file.
+func (s *Sink) Handle(ctx context.Context, row Row, decode Decoder, logger *zap.SugaredLogger) error {
+	var data *domain.FileData
+	if row.Err == nil {
+		data, row.Err = decode(row)
+	}
+	if row.Err != nil {
+		if errors.Is(row.Err, ErrUnreadable) {
+			return row.Err
+		}
+		logger.Warnf("Rejected row %s: %v", row.position(), row.Err)
+		return s.Reject(ctx, row)
+	}
+	if data == nil {
+		return nil
+	}
+	return s.Accept(ctx, row, data)
+}
+
+// OnReject sets a function told about every rejected row, whatever the policy. Its errors abort the file.
+func (s *Sink) OnReject(observe func(Rejection) error) {
+	s.observe = observe
+}
+
+// Reject counts a bad row. It returns an error when the policy fails the file.
+func (s *Sink) Reject(ctx context.Context, row Row) error {
+	if s.observe != nil {
+		if err := s.observe(row.Rejection()); err != nil {
+			return err
+		}
+	}
+	if err := s.report.Reject(row); err != nil {
+		return err
+	}
+	if len(s.report.Rejections)-s.flushed < s.size {
+		return nil
+	}
+	return s.Flush(ctx)
+}
+
+// Flush hands the pending rows to the handler.
+func (s *Sink) Flush(ctx context.Context) error {
+	rejected := s.report.Rejections[s.flushed:]
+	if len(s.chunk) == 0 && len(rejected) == 0 {
+		return nil
+	}
+	if err := s.handle(ctx, s.chunk, rejected); err != nil {
+		return err
+	}
+	s.chunk = make([]*domain.FileData, 0, s.size)
+	s.flushed = len(s.report.Rejections)
+	return nil
+}
+
+// Send hands row to ch. It returns false when ctx is done first, so the reading must stop.
+func Send(ctx context.Context, ch chan<- Row, row Row) bool {
+	select {
+	case ch <- row:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,35 @@
+package rejectreport
+
+import (
+	"strings"
+)
+
+// Options represents where the reports of rejected rows are uploaded.
+type Options struct {
+	// Bucket receives the reports. Empty disables them.
+	Bucket string
+	// Prefix is prepended to the key of the file to name its report.
+	Prefix string
+	Format Format
+}
+
+// Enabled reports whether reports are uploaded.
+func (o Options) Enabled() bool {
+	return o.Bucket != ""
+}
+
+// Key returns the key of the report of the file with the given key.
+func (o Options) Key(key string) string {
+	if o.Prefix == "" {
+		return key + o.Format.Extension()
+	}
+	return strings.TrimSuffix(o.Prefix, "/") + "/" + strings.TrimPrefix(key, "/") + o.Format.Extension()
+}
+
+// IsReport reports whether an object is a report, so it isn't ingested when the results bucket is consumed too.
+func (o Options) IsReport(bucket, key string) bool {
+	if !o.Enabled() || bucket != o.Bucket || !strings.HasSuffix(key, o.Format.Extension()) {
+		return false
+	}
+	return o.Prefix == "" || strings.HasPrefix(key, strings.TrimSuffix(o.Prefix, "/")+"/")
+}
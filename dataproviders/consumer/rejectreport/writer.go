@@ -0,0 +1,115 @@
+package rejectreport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"strconv"
+)
+
+// Format is the format of a report of rejected rows.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	JSON Format = "json"
+)
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case CSV, JSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown rejects report format %q", name)
+}
+
+// Extension returns the suffix appended to the key of a file to name its report.
+func (f Format) Extension() string {
+	return ".errors." + string(f)
+}
+
+// ContentType returns the media type of the reports.
+func (f Format) ContentType() string {
+	if f == JSON {
+		return "application/json"
+	}
+	return "text/csv"
+}
+
+// entry represents a rejected row in a json report.
+type entry struct {
+	Sheet  string `json:"sheet,omitempty"`
+	Line   int    `json:"line"`
+	Raw    string `json:"raw"`
+	Reason string `json:"reason"`
+}
+
+// Writer writes the rejected rows of a file as they come. The report is complete once the writer is closed.
+type Writer struct {
+	w      io.Writer
+	format Format
+	csv    *csv.Writer
+	count  int
+}
+
+// NewWriter returns a writer of a report in format to w.
+func NewWriter(w io.Writer, format Format) *Writer {
+	return &Writer{w: w, format: format}
+}
+
+// Count returns the number of rows written.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Write adds a rejected row to the report.
+func (w *Writer) Write(r record.Rejection) error {
+	defer func() { w.count++ }()
+
+	if w.format == JSON {
+		raw, err := json.Marshal(entry{Sheet: r.Sheet, Line: r.Line, Raw: r.Raw, Reason: r.Reason})
+		if err != nil {
+			return err
+		}
+		separator := ",\n"
+		if w.count == 0 {
+			separator = "[\n"
+		}
+		_, err = fmt.Fprintf(w.w, "%s%s", separator, raw)
+		return err
+	}
+
+	if err := w.header(); err != nil {
+		return err
+	}
+	return w.csv.Write([]string{strconv.Itoa(r.Line), r.Sheet, r.Raw, r.Reason})
+}
+
+// Close ends the report. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if w.format == JSON {
+		end := "\n]\n"
+		if w.count == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(w.w, end)
+		return err
+	}
+	if err := w.header(); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+// header starts a csv report.
+func (w *Writer) header() error {
+	if w.csv != nil {
+		return nil
+	}
+	w.csv = csv.NewWriter(w.w)
+	return w.csv.Write([]string{"line", "sheet", "raw", "reason"})
+}
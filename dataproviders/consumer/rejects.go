@@ -0,0 +1,153 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"service-worker-sqs-s3-postgres/dataproviders/awss3/downloader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/rejectreport"
+	"service-worker-sqs-s3-postgres/dataproviders/utils"
+
+	"github.com/spf13/afero"
+	"go.uber.org/zap"
+)
+
+// rejectsReport writes the rejected rows of a file to a local file, created on the first rejection, to be uploaded
+// once the rows of the file are written.
+type rejectsReport struct {
+	download *downloader.S3Downloader
+	opts     rejectreport.Options
+	key      string
+	filename string
+	file     afero.File
+	writer   *rejectreport.Writer
+	uploaded bool
+	// event is the record the report belongs to, whose location is cleared when the report can't be uploaded.
+	event *s3Event
+	log   *zap.SugaredLogger
+}
+
+// newRejectsReport returns the report of the rejected rows of a record.
+func (s *SQSSource) newRejectsReport(s3Event *s3Event) *rejectsReport {
+	key := s3Event.key
+	if s3Event.entryName != "" {
+		key += "/" + s3Event.entryName
+	}
+	return &rejectsReport{
+		download: s.download,
+		opts:     s.reports,
+		key:      s.reports.Key(key),
+		event:    s3Event,
+		log:      s3Event.log,
+	}
+}
+
+// write adds a rejected row to the report.
+func (r *rejectsReport) write(rejection record.Rejection) error {
+	if r.writer == nil {
+		filename, file, err := r.download.Create(r.key)
+		if err != nil {
+			return fmt.Errorf("error creating rejected rows report: %w", err)
+		}
+		r.filename, r.file = filename, file
+		r.writer = rejectreport.NewWriter(file, r.opts.Format)
+	}
+	return r.writer.Write(rejection)
+}
+
+// location returns where the report is uploaded, empty when no row was rejected.
+func (r *rejectsReport) location() string {
+	if r.writer == nil {
+		return ""
+	}
+	return fmt.Sprintf("s3://%s/%s", r.opts.Bucket, r.key)
+}
+
+// upload uploads the report, if any row was rejected.
+func (r *rejectsReport) upload(ctx context.Context) error {
+	if r.writer == nil || r.uploaded {
+		return nil
+	}
+	if r.file != nil {
+		if err := r.writer.Close(); err != nil {
+			return err
+		}
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+
+	if err := r.download.Upload(ctx, r.opts.Bucket, r.key, r.opts.Format.ContentType(), r.filename); err != nil {
+		return err
+	}
+	r.uploaded = true
+	r.log.Infof("Step 4 - Rejected rows report uploaded to %s", r.location())
+	return nil
+}
+
+// discard deletes the local copy of the report.
+func (r *rejectsReport) discard() {
+	if r.file != nil {
+		utils.Close(r.file, r.log)
+	}
+	if r.filename == "" {
+		return
+	}
+	if err := r.download.Delete(r.filename); err != nil {
+		r.log.Errorf("Error deleting local file: %v", err)
+	}
+}
+
+// rejectsReports are the reports of a record and of the entries of its archive, uploaded together once their rows
+// are written.
+type rejectsReports struct {
+	reports []*rejectsReport
+}
+
+// add adds the report of the record or of an entry.
+func (rs *rejectsReports) add(r *rejectsReport) {
+	rs.reports = append(rs.reports, r)
+}
+
+// upload uploads every report with rejected rows.
+func (rs *rejectsReports) upload(ctx context.Context) error {
+	for _, r := range rs.reports {
+		if err := r.upload(ctx); err != nil {
+			return fmt.Errorf("error uploading rejected rows report: %w", err)
+		}
+	}
+	return nil
+}
+
+// keep uploads the reports of a file rejected by the reject policy, whose rows were rolled back. Reports that can't
+// be uploaded are left out of the metadata of their record.
+func (rs *rejectsReports) keep(ctx context.Context) {
+	for _, r := range rs.reports {
+		if err := r.upload(ctx); err != nil {
+			r.log.Errorf("Error uploading rejected rows report: %v", err)
+			r.event.rejectsReport = ""
+		}
+	}
+}
+
+// remove deletes the uploaded reports from S3 when the rows they describe were rolled back.
+func (rs *rejectsReports) remove(ctx context.Context) {
+	for _, r := range rs.reports {
+		if !r.uploaded {
+			continue
+		}
+		if err := r.download.DeleteObject(ctx, r.opts.Bucket, r.key); err != nil {
+			r.log.Errorf("Error deleting rejected rows report: %v", err)
+			continue
+		}
+		r.uploaded = false
+	}
+}
+
+// discard deletes the local copies of the reports.
+func (rs *rejectsReports) discard() {
+	for _, r := range rs.reports {
+		r.discard()
+	}
+}
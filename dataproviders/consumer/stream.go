@@ -0,0 +1,44 @@
+package consumer
+
+import (
+	"errors"
+	"io"
+	"service-worker-sqs-s3-postgres/dataproviders/awss3/downloader"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+)
+
+// unreadableReader marks the errors of a stream as errors of the file being read.
+type unreadableReader struct {
+	r io.Reader
+}
+
+func (u unreadableReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = record.Unreadable(err)
+	}
+	return n, err
+}
+
+// interruptedReader marks the errors of the body of an object as interruptions, so a file that stops arriving
+// halfway is retried rather than rejected.
+type interruptedReader struct {
+	r io.Reader
+}
+
+func (i interruptedReader) Read(p []byte) (int, error) {
+	n, err := i.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = record.Interrupted(err)
+	}
+	return n, err
+}
+
+// verifyStream reads the rest of a streamed body, since parsers may stop before its end, like after the closing
+// bracket of a json array, and returns the checksum it was verified against.
+func verifyStream(body io.Reader, verifier *downloader.Verifier) (string, error) {
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return "", err
+	}
+	return verifier.Verify()
+}
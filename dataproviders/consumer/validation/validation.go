@@ -0,0 +1,201 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits of the columns of the filedata table.
+const (
+	maxTextLength = 200
+	minID         = -1 << 31
+	maxID         = 1<<31 - 1
+)
+
+var (
+	// ErrInvalidRow means a row breaks a validation rule.
+	ErrInvalidRow = errors.New("invalid row")
+
+	errInvalidRules = errors.New("invalid validation rules")
+)
+
+// Rule represents the checks of a field of the rows. Field is id, message, owner or the name of an extra column.
+// Every check but Required passes on empty values.
+type Rule struct {
+	Field    string `json:"field"`
+	Required bool   `json:"required,omitempty"`
+	// Pattern is a regular expression the whole value must match.
+	Pattern string `json:"pattern,omitempty"`
+	// MaxLength is the maximum number of characters of the value.
+	MaxLength int `json:"maxLength,omitempty"`
+	// Min and Max bound numeric values.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Allowed lists the only values accepted.
+	Allowed []string `json:"allowed,omitempty"`
+	// Unique values can't be repeated within a file.
+	Unique bool `json:"unique,omitempty"`
+
+	pattern *regexp.Regexp
+	allowed map[string]bool
+}
+
+// Rules represents the validation rules of the rows of every file.
+type Rules []Rule
+
+// Default returns the rules enforcing the limits of the filedata table, checked after any other rule.
+func Default() Rules {
+	minimum, maximum := float64(minID), float64(maxID)
+	return Rules{
+		{Field: mapping.FieldID, Min: &minimum, Max: &maximum},
+		{Field: mapping.FieldMessage, MaxLength: maxTextLength},
+		{Field: mapping.FieldOwner, MaxLength: maxTextLength},
+	}
+}
+
+// Parse returns the rules listed as a json array in raw followed by the Default rules.
+func Parse(raw string) (Rules, error) {
+	rules := make(Rules, 0)
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			return nil, fmt.Errorf("%w: must be a json array: %v", errInvalidRules, err)
+		}
+	}
+	rules = append(rules, Default()...)
+
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// Validator returns a validator of the rows of a single file.
+func (r Rules) Validator() *Validator {
+	return &Validator{rules: r, seen: make(map[int]map[string]bool)}
+}
+
+func (r *Rule) compile() error {
+	r.Field = strings.TrimSpace(r.Field)
+	switch {
+	case r.Field == "":
+		return fmt.Errorf("%w: rule without field", errInvalidRules)
+	case r.MaxLength < 0:
+		return fmt.Errorf("%w: field %s has a negative maxLength", errInvalidRules, r.Field)
+	case r.Min != nil && r.Max != nil && *r.Min > *r.Max:
+		return fmt.Errorf("%w: field %s has min greater than max", errInvalidRules, r.Field)
+	}
+
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %v", errInvalidRules, r.Field, err)
+		}
+		r.pattern = pattern
+	}
+	if len(r.Allowed) > 0 {
+		r.allowed = make(map[string]bool, len(r.Allowed))
+		for _, v := range r.Allowed {
+			r.allowed[v] = true
+		}
+	}
+	return nil
+}
+
+// Validator checks rows against the rules, remembering the values of unique fields seen in the file.
+type Validator struct {
+	rules Rules
+	// seen are the values of each unique rule, by position of the rule.
+	seen map[int]map[string]bool
+}
+
+// Validate returns an error wrapping ErrInvalidRow with the reason of the first rule the row breaks.
+// The values of unique fields are only remembered once the row passes every rule, so a rejected row
+// doesn't make a later valid one look repeated.
+func (v *Validator) Validate(data *domain.FileData) error {
+	unique := make(map[int]string)
+	for i, rule := range v.rules {
+		raw := valueOf(data, rule.Field)
+		if err := rule.check(raw); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidRow, rule.Field, err)
+		}
+		value := strings.TrimSpace(raw)
+		if !rule.Unique || value == "" {
+			continue
+		}
+		if v.seen[i][value] {
+			return fmt.Errorf("%w: %s %q is repeated in the file", ErrInvalidRow, rule.Field, value)
+		}
+		unique[i] = value
+	}
+
+	for i, value := range unique {
+		if v.seen[i] == nil {
+			v.seen[i] = make(map[string]bool)
+		}
+		v.seen[i][value] = true
+	}
+	return nil
+}
+
+// check returns why a value breaks the rule. Values are trimmed, except to count their length as stored.
+func (r Rule) check(raw string) error {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		if r.Required {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if r.MaxLength > 0 && utf8.RuneCountInString(raw) > r.MaxLength {
+		return fmt.Errorf("is longer than %d characters", r.MaxLength)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		return fmt.Errorf("%q doesn't match %s", value, r.Pattern)
+	}
+	if r.allowed != nil && !r.allowed[value] {
+		return fmt.Errorf("%q isn't one of %s", value, strings.Join(r.Allowed, ", "))
+	}
+	if r.Min != nil || r.Max != nil {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", value)
+		}
+		if r.Min != nil && n < *r.Min {
+			return fmt.Errorf("%s is less than %s", value, formatNumber(*r.Min))
+		}
+		if r.Max != nil && n > *r.Max {
+			return fmt.Errorf("%s is greater than %s", value, formatNumber(*r.Max))
+		}
+	}
+	return nil
+}
+
+// ---------- Helpers ------------ //
+
+func valueOf(data *domain.FileData, field string) string {
+	switch strings.ToLower(field) {
+	case mapping.FieldID:
+		if data.ID == nil {
+			return ""
+		}
+		return strconv.FormatInt(*data.ID, 10)
+	case mapping.FieldMessage:
+		return data.Message
+	case mapping.FieldOwner:
+		return data.Owner
+	}
+	return data.Extra[field]
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,144 @@
+package validation
+
+import (
+	"errors"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"strings"
+	"testing"
+)
+
+func TestValidatorValidate(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name  string
+		rules string
+		rows  []*domain.FileData
+		// want is whether each row is valid.
+		want []bool
+	}{
+		{
+			name:  "default limits",
+			rules: "",
+			rows: []*domain.FileData{
+				{ID: id(1), Message: "hola", Owner: "ventas"},
+				{ID: id(maxID + 1), Message: "hola", Owner: "ventas"},
+				{ID: id(minID - 1), Message: "hola", Owner: "ventas"},
+				{ID: id(2), Message: strings.Repeat("ñ", maxTextLength), Owner: "ventas"},
+				{ID: id(3), Message: strings.Repeat("a", maxTextLength+1), Owner: "ventas"},
+			},
+			want: []bool{true, false, false, true, false},
+		},
+		{
+			name:  "required",
+			rules: `[{"field":"owner","required":true}]`,
+			rows: []*domain.FileData{
+				{Owner: "ventas"},
+				{Owner: "  "},
+				{},
+			},
+			want: []bool{true, false, false},
+		},
+		{
+			name:  "pattern matches the whole trimmed value",
+			rules: `[{"field":"message","pattern":"[a-z]+"}]`,
+			rows: []*domain.FileData{
+				{Message: " hola "},
+				{Message: "hola1"},
+				{Message: ""},
+			},
+			want: []bool{true, false, true},
+		},
+		{
+			name:  "allowed values",
+			rules: `[{"field":"owner","allowed":["ventas","soporte"]}]`,
+			rows: []*domain.FileData{
+				{Owner: "soporte"},
+				{Owner: "Ventas"},
+			},
+			want: []bool{true, false},
+		},
+		{
+			name:  "numeric bounds of an extra column",
+			rules: `[{"field":"Price","min":0,"max":9.5}]`,
+			rows: []*domain.FileData{
+				{Extra: map[string]string{"Price": "9.5"}},
+				{Extra: map[string]string{"Price": "-0.1"}},
+				{Extra: map[string]string{"Price": "10"}},
+				{Extra: map[string]string{"Price": "diez"}},
+				{},
+			},
+			want: []bool{true, false, false, false, true},
+		},
+		{
+			name:  "unique values within the file",
+			rules: `[{"field":"id","unique":true}]`,
+			rows: []*domain.FileData{
+				{ID: id(1)},
+				{ID: id(2)},
+				{ID: id(1)},
+				{},
+				{},
+			},
+			want: []bool{true, true, false, true, true},
+		},
+		{
+			name:  "unique value of a rejected row isn't remembered",
+			rules: `[{"field":"message","unique":true},{"field":"owner","required":true}]`,
+			rows: []*domain.FileData{
+				{Message: "hola"},
+				{Message: "hola", Owner: "ventas"},
+				{Message: " hola ", Owner: "soporte"},
+			},
+			want: []bool{false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := Parse(tt.rules)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			v := rules.Validator()
+			for i, row := range tt.rows {
+				err := v.Validate(row)
+				if valid := err == nil; valid != tt.want[i] {
+					t.Fatalf("row %d: Validate() error = %v, want valid %v", i, err, tt.want[i])
+				}
+				if err != nil && !errors.Is(err, ErrInvalidRow) {
+					t.Fatalf("row %d: Validate() error = %v, want %v", i, err, ErrInvalidRow)
+				}
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr error
+	}{
+		{name: "no rules", raw: "", want: len(Default())},
+		{name: "rules before the defaults", raw: `[{"field":"owner","required":true}]`, want: len(Default()) + 1},
+		{name: "not an array", raw: `{"field":"owner"}`, wantErr: errInvalidRules},
+		{name: "rule without field", raw: `[{"field":" ","required":true}]`, wantErr: errInvalidRules},
+		{name: "negative max length", raw: `[{"field":"owner","maxLength":-1}]`, wantErr: errInvalidRules},
+		{name: "min greater than max", raw: `[{"field":"id","min":2,"max":1}]`, wantErr: errInvalidRules},
+		{name: "invalid pattern", raw: `[{"field":"owner","pattern":"("}]`, wantErr: errInvalidRules},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.raw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Parse() returned %d rules, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package xlsxreader
+
+import (
+	"context"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/parser"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+
+	"go.uber.org/zap"
+)
+
+// Name is the name of the xlsx parser.
+const Name = "xlsx"
+
+var (
+	// Extensions are the extensions of xlsx workbooks.
+	Extensions = []string{".xlsx"}
+	// ContentTypes are the media types of xlsx workbooks.
+	ContentTypes = []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"}
+)
+
+// Parser reads the selected sheets of xlsx workbooks.
+type Parser struct {
+	opts    Options
+	mapping mapping.Mapping
+}
+
+// NewParser returns a xlsx parser.
+func NewParser(opts Options, m mapping.Mapping) *Parser {
+	return &Parser{opts: opts, mapping: m}
+}
+
+// Parse streams the rows of a workbook to sink. Streamed workbooks are spooled first, so they are bounded by the spool.
+func (p *Parser) Parse(ctx context.Context, in parser.Input, sink *record.Sink, logger *zap.SugaredLogger) (string, error) {
+	logger.Info("Step 4 - Reading file as XLSX")
+	r, size, err := in.RandomAccess()
+	if err != nil {
+		return "", err
+	}
+	return "", Read(ctx, r, size, p.opts, p.mapping, sink, logger)
+}
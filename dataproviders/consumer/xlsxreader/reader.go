@@ -0,0 +1,143 @@
+package xlsxreader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/mapping"
+	"service-worker-sqs-s3-postgres/dataproviders/consumer/record"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+)
+
+// AllSheets selects every sheet of a workbook.
+const AllSheets = "*"
+
+// Options represents which rows of a workbook are read.
+type Options struct {
+	// Sheet is the name of the sheet read, the first sheet when empty and every sheet when AllSheets.
+	Sheet string
+	// SkipRows is the number of rows skipped at the top of every sheet, before the header.
+	SkipRows int
+	// MaxBytes is the size of the largest workbook read, since it is held in memory while it is read.
+	MaxBytes int64
+}
+
+// Read streams the rows of the sheets of the workbook of size bytes in r to sink. Every sheet starts with a header
+// after the skipped rows, and its columns are matched to filedata by m. Rows carry the sheet name and row number.
+func Read(ctx context.Context, r io.ReaderAt, size int64, opts Options, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	if opts.MaxBytes > 0 && size > opts.MaxBytes {
+		return record.Unreadable(fmt.Errorf("workbook of %d bytes exceeds the limit of %d bytes", size, opts.MaxBytes))
+	}
+
+	f, err := excelize.OpenReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return record.Unreadable(err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.Errorf("Error closing workbook: %v", err)
+		}
+	}()
+
+	sheets, err := selectSheets(f, opts.Sheet)
+	if err != nil {
+		return record.Unreadable(err)
+	}
+
+	for _, sheet := range sheets {
+		logger.Debugf("Reading sheet %s", sheet)
+		if err = readSheet(ctx, f, sheet, opts.SkipRows, m, sink, logger); err != nil {
+			return err
+		}
+	}
+	return sink.Flush(ctx)
+}
+
+// ---------- Helpers ------------ //
+
+func selectSheets(f *excelize.File, sheet string) ([]string, error) {
+	sheets := f.GetSheetList()
+	switch {
+	case len(sheets) == 0:
+		return nil, record.ErrEmptyFile
+	case sheet == AllSheets:
+		return sheets, nil
+	case sheet == "":
+		return sheets[:1], nil
+	}
+
+	for _, s := range sheets {
+		if strings.EqualFold(s, sheet) {
+			return []string{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %q not found", sheet)
+}
+
+func readSheet(ctx context.Context, f *excelize.File, sheet string, skip int, m mapping.Mapping, sink *record.Sink, logger *zap.SugaredLogger) error {
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return record.Unreadable(err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logger.Errorf("Error closing sheet %s: %v", sheet, err)
+		}
+	}()
+
+	var binding *mapping.Binding
+	var columns int
+	decode := func(row record.Row) (*domain.FileData, error) {
+		return binding.FileData(row.Fields)
+	}
+	for line := 1; rows.Next(); line++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		cells, err := rows.Columns()
+		if err != nil {
+			return record.Unreadable(fmt.Errorf("sheet %s row %d: %w", sheet, line, err))
+		}
+		if line <= skip || isEmpty(cells) {
+			continue
+		}
+
+		if binding == nil {
+			if binding, err = m.Bind(cells); err != nil {
+				return record.Unreadable(fmt.Errorf("sheet %s: %w", sheet, err))
+			}
+			columns = len(cells)
+			continue
+		}
+
+		// trailing empty cells aren't stored in the workbook.
+		for len(cells) < columns {
+			cells = append(cells, "")
+		}
+		row := record.Row{Sheet: sheet, Line: line, Fields: cells, Raw: strings.Join(cells, ",")}
+		if err = sink.Handle(ctx, row, decode, logger); err != nil {
+			return err
+		}
+	}
+	if err = rows.Error(); err != nil {
+		return record.Unreadable(err)
+	}
+	if binding == nil {
+		logger.Warnf("Sheet %s has no header", sheet)
+	}
+	return nil
+}
+
+func isEmpty(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package mapper
 
 import (
+	"encoding/json"
 	"service-worker-sqs-s3-postgres/core/domain"
 	"service-worker-sqs-s3-postgres/core/domain/entity"
 	"time"
@@ -13,6 +14,7 @@ func ToDomainFileData(f *entity.FileData) *domain.FileData {
 		Message: f.Message,
 		Owner:   f.Owner,
 		Date:    f.Date,
+		Extra:   toExtraMap(f.Extra),
 	}
 }
 
@@ -22,5 +24,28 @@ func ToEntityFileData(f *domain.FileData) *entity.FileData {
 		Message: f.Message,
 		Owner:   f.Owner,
 		Date:    time.Now().Format(time.RFC3339),
+		Extra:   toExtraText(f.Extra),
 	}
 }
+
+func toExtraMap(extra string) map[string]string {
+	if extra == "" {
+		return nil
+	}
+	m := make(map[string]string)
+	if err := json.Unmarshal([]byte(extra), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+func toExtraText(extra map[string]string) string {
+	if len(extra) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(extra)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
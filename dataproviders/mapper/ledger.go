@@ -0,0 +1,42 @@
+package mapper
+
+import (
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/core/domain/entity"
+	"service-worker-sqs-s3-postgres/dataproviders/utils"
+	"time"
+)
+
+// ToDomainProcessedObject convert model the postgres processed object to domain processed object.
+func ToDomainProcessedObject(p *entity.ProcessedObject) *domain.ProcessedObject {
+	return &domain.ProcessedObject{
+		Bucket:      p.Bucket,
+		Key:         p.Key,
+		VersionID:   p.VersionID,
+		Fingerprint: p.Fingerprint,
+		TrackID:     p.TrackID,
+		Status:      p.Status,
+	}
+}
+
+func ToEntityProcessedObject(p *domain.ProcessedObject) *entity.ProcessedObject {
+	return &entity.ProcessedObject{
+		Bucket:      p.Bucket,
+		Key:         p.Key,
+		VersionID:   p.VersionID,
+		Fingerprint: p.Fingerprint,
+		TrackID:     p.TrackID,
+		Status:      p.Status,
+		ClaimedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func ToEntityObjectSequencer(o *domain.ObjectSequencer) *entity.ObjectSequencer {
+	return &entity.ObjectSequencer{
+		Bucket:    o.Bucket,
+		Key:       o.Key,
+		Sequencer: utils.PadSequencer(o.Sequencer),
+		TrackID:   o.TrackID,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+}
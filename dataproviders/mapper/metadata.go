@@ -3,25 +3,48 @@ package mapper
 import (
 	"service-worker-sqs-s3-postgres/core/domain"
 	"service-worker-sqs-s3-postgres/core/domain/entity"
+	"time"
 )
 
 // ToDomainMetaData convert domain metadata to model the postgres metadata .
 func ToDomainMetaData(m *entity.MetaData) *domain.MetaData {
 	return &domain.MetaData{
-		TrackID:  m.TrackID,
-		Bucket:   m.Bucket,
-		FileName: m.FileName,
-		Key:      m.Key,
-		Size:     m.Size,
+		TrackID:       m.TrackID,
+		ParentTrackID: m.ParentTrackID,
+		Bucket:        m.Bucket,
+		FileName:      m.FileName,
+		Key:           m.Key,
+		Size:          m.Size,
+		Outcome:       m.Outcome,
+		Accepted:      m.Accepted,
+		Rejected:      m.Rejected,
+		Dialect:       m.Dialect,
+		Compression:   m.Compression,
+		RejectsReport: m.RejectsReport,
+		Checksum:      m.Checksum,
+		VersionID:     m.VersionID,
+		Sequencer:     m.Sequencer,
+		RecordedAt:    m.RecordedAt,
 	}
 }
 
 func ToEntityMetaData(f *domain.MetaData) *entity.MetaData {
 	return &entity.MetaData{
-		TrackID:  f.TrackID,
-		Bucket:   f.Bucket,
-		FileName: f.FileName,
-		Key:      f.Key,
-		Size:     f.Size,
+		TrackID:       f.TrackID,
+		ParentTrackID: f.ParentTrackID,
+		Bucket:        f.Bucket,
+		FileName:      f.FileName,
+		Key:           f.Key,
+		Size:          f.Size,
+		Outcome:       f.Outcome,
+		Accepted:      f.Accepted,
+		Rejected:      f.Rejected,
+		Dialect:       f.Dialect,
+		Compression:   f.Compression,
+		RejectsReport: f.RejectsReport,
+		Checksum:      f.Checksum,
+		VersionID:     f.VersionID,
+		Sequencer:     f.Sequencer,
+		RecordedAt:    time.Now().UTC().Format(time.RFC3339),
 	}
 }
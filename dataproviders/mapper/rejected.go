@@ -0,0 +1,27 @@
+package mapper
+
+import (
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/core/domain/entity"
+)
+
+// ToDomainRejectedRow convert model the postgres rejected row to domain rejected row.
+func ToDomainRejectedRow(r *entity.RejectedRow) *domain.RejectedRow {
+	return &domain.RejectedRow{
+		TrackID: r.TrackID,
+		Sheet:   r.Sheet,
+		Line:    r.Line,
+		Raw:     r.Raw,
+		Reason:  r.Reason,
+	}
+}
+
+func ToEntityRejectedRow(r *domain.RejectedRow) *entity.RejectedRow {
+	return &entity.RejectedRow{
+		TrackID: r.TrackID,
+		Sheet:   r.Sheet,
+		Line:    r.Line,
+		Raw:     r.Raw,
+		Reason:  r.Reason,
+	}
+}
@@ -68,7 +68,7 @@ func (client *ClientDB) Open() error {
 		sqlDB.SetConnMaxIdleTime(10)
 		sqlDB.SetMaxOpenConns(10)
 
-		err = dbs.AutoMigrate(entity.FileData{}, entity.MetaData{})
+		err = dbs.AutoMigrate(entity.FileData{}, entity.MetaData{}, entity.ProcessedObject{}, entity.ObjectSequencer{}, entity.RejectedRow{})
 		if err != nil {
 			return errors.Wrapf(err, "Error migrating postgres : %v", err.Error())
 		}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"gorm.io/gorm/clause"
 	"service-worker-sqs-s3-postgres/core/domain"
 	"service-worker-sqs-s3-postgres/core/domain/entity"
@@ -11,21 +12,34 @@ import (
 
 type IFileDataRepository interface {
 	GetID(ID string) (*domain.FileData, error)
-	Insert(filedata []*domain.FileData) error
+	Insert(ctx context.Context, filedata []*domain.FileData) error
 }
 
 // FileDataRepository encapsulates all the data needed to the persistence in the filedata table.
 type FileDataRepository struct {
 	db *postgres.ClientDB
+	// table is where the rows are inserted, the filedata table unless the repository belongs to another pipeline.
+	table string
 }
 
 // NewFileDataRepository instance the connection to the postgres.
 func NewFileDataRepository(db *postgres.ClientDB) *FileDataRepository {
+	return NewFileDataTableRepository(db, entity.FileData{}.TableName())
+}
+
+// NewFileDataTableRepository instance the connection to the postgres for rows inserted in table.
+func NewFileDataTableRepository(db *postgres.ClientDB, table string) *FileDataRepository {
 	return &FileDataRepository{
-		db: db,
+		db:    db,
+		table: table,
 	}
 }
 
+// Migrate creates or updates the table of the repository.
+func (er *FileDataRepository) Migrate() error {
+	return er.db.DB.Table(er.table).AutoMigrate(&entity.FileData{})
+}
+
 // GetID return the filedata by ID.
 func (er *FileDataRepository) GetID(ID string) (*domain.FileData, error) {
 	filedata := &entity.FileData{}
@@ -39,7 +53,7 @@ func (er *FileDataRepository) GetID(ID string) (*domain.FileData, error) {
 }
 
 // Insert records a filedata in the database.
-func (er *FileDataRepository) Insert(filedata []*domain.FileData) error {
+func (er *FileDataRepository) Insert(ctx context.Context, filedata []*domain.FileData) error {
 
 	files := make([]*entity.FileData, 0)
 
@@ -47,12 +61,11 @@ func (er *FileDataRepository) Insert(filedata []*domain.FileData) error {
 		files = append(files, mapper.ToEntityFileData(v))
 	}
 
-	r := er.db.DB.Clauses(clause.OnConflict{
+	err := er.db.Conn(ctx).Table(er.table).Clauses(clause.OnConflict{
 		UpdateAll: true,
-	}).Create(&files)
-	if r.Error != nil {
-		r.Rollback()
-		return r.Error
+	}).Create(&files).Error
+	if err != nil {
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/core/domain/entity"
+	"service-worker-sqs-s3-postgres/dataproviders/mapper"
+	"service-worker-sqs-s3-postgres/dataproviders/postgres"
+	"time"
+)
+
+// ErrClaimLost means the claim of an object was taken over by another record before it was ingested.
+var ErrClaimLost = errors.New("claim of object lost")
+
+type ILedgerRepository interface {
+	Claim(ctx context.Context, object *domain.ProcessedObject, staleBefore time.Time) (*domain.ProcessedObject, error)
+	Finalise(ctx context.Context, object *domain.ProcessedObject) error
+	Release(ctx context.Context, object *domain.ProcessedObject) error
+	LastSequencer(ctx context.Context, bucket, key string) (string, error)
+	Advance(ctx context.Context, sequencer *domain.ObjectSequencer) (bool, error)
+}
+
+// LedgerRepository encapsulates all the data needed to the persistence in the processed_objects table.
+type LedgerRepository struct {
+	db *postgres.ClientDB
+}
+
+// NewLedgerRepository instance the connection to the postgres.
+func NewLedgerRepository(db *postgres.ClientDB) *LedgerRepository {
+	return &LedgerRepository{
+		db: db,
+	}
+}
+
+// Claim records that the record of object.TrackID is ingesting object, unless the object is already claimed.
+// A pending claim is taken over when it belongs to the same record, retried after a crash, or it was made before
+// staleBefore. It returns nil once claimed, or else the ledger entry holding the object.
+func (er *LedgerRepository) Claim(ctx context.Context, object *domain.ProcessedObject, staleBefore time.Time) (*domain.ProcessedObject, error) {
+	claim := mapper.ToEntityProcessedObject(object)
+	claim.Status = domain.ClaimPending
+
+	r := er.db.Conn(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket"}, {Name: "key"}, {Name: "versionid"}, {Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"trackid", "claimedat"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: "processed_objects", Name: "status"}, Value: domain.ClaimPending},
+			clause.Or(
+				clause.Expr{SQL: "processed_objects.trackid = excluded.trackid"},
+				clause.Lt{Column: clause.Column{Table: "processed_objects", Name: "claimedat"}, Value: staleBefore.UTC().Format(time.RFC3339)},
+			),
+		}},
+	}).Create(claim)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	if r.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	holder := &entity.ProcessedObject{}
+	err := er.db.Conn(ctx).
+		Where("bucket = ? AND key = ? AND versionid = ? AND fingerprint = ?", object.Bucket, object.Key, object.VersionID, object.Fingerprint).
+		Take(holder).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return mapper.ToDomainProcessedObject(holder), nil
+}
+
+// Finalise records that the record holding the claim of an object ingested it. It fails with ErrClaimLost when the
+// claim was taken over meanwhile.
+func (er *LedgerRepository) Finalise(ctx context.Context, object *domain.ProcessedObject) error {
+	r := er.db.Conn(ctx).Model(&entity.ProcessedObject{}).
+		Where("bucket = ? AND key = ? AND versionid = ? AND fingerprint = ? AND trackid = ? AND status = ?", object.Bucket, object.Key, object.VersionID, object.Fingerprint, object.TrackID, domain.ClaimPending).
+		Updates(map[string]interface{}{"status": domain.ClaimIngested, "processedat": time.Now().Format(time.RFC3339)})
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+// Release drops the pending claim of an object by a record that didn't ingest it.
+func (er *LedgerRepository) Release(ctx context.Context, object *domain.ProcessedObject) error {
+	return er.db.Conn(ctx).
+		Where("bucket = ? AND key = ? AND versionid = ? AND fingerprint = ? AND trackid = ? AND status = ?", object.Bucket, object.Key, object.VersionID, object.Fingerprint, object.TrackID, domain.ClaimPending).
+		Delete(&entity.ProcessedObject{}).Error
+}
+
+// LastSequencer return the padded sequencer of the last event ingested for an object, or empty when there's none.
+func (er *LedgerRepository) LastSequencer(ctx context.Context, bucket, key string) (string, error) {
+	last := &entity.ObjectSequencer{}
+
+	err := er.db.Conn(ctx).
+		Where("bucket = ? AND key = ?", bucket, key).
+		Take(last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return last.Sequencer, nil
+}
+
+// Advance records the sequencer of an event of an object, unless a later one is already recorded, and reports
+// whether it was recorded. The row of the object stays locked until the transaction of ctx ends, so events of the
+// same object are ingested one at a time, and an older event waiting for a newer one finds it recorded.
+func (er *LedgerRepository) Advance(ctx context.Context, sequencer *domain.ObjectSequencer) (bool, error) {
+
+	last := mapper.ToEntityObjectSequencer(sequencer)
+
+	r := er.db.Conn(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sequencer", "trackid", "updatedat"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "excluded.sequencer >= object_sequencers.sequencer"},
+		}},
+	}).Create(last)
+	if r.Error != nil {
+		return false, r.Error
+	}
+	return r.RowsAffected > 0, nil
+}
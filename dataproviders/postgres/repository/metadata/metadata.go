@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"gorm.io/gorm/clause"
 	"service-worker-sqs-s3-postgres/core/domain"
 	"service-worker-sqs-s3-postgres/core/domain/entity"
 	"service-worker-sqs-s3-postgres/core/domain/exceptions"
@@ -10,7 +12,7 @@ import (
 
 type IMetaDataRepository interface {
 	GetID(trackID string) (*domain.MetaData, error)
-	Insert(metadata domain.MetaData) error
+	Insert(ctx context.Context, metadata *domain.MetaData) error
 }
 
 // MetaDataRepository encapsulates all the data needed to the persistence in the filedata table.
@@ -25,11 +27,15 @@ func NewMetaDataRepository(db *postgres.ClientDB) *MetaDataRepository {
 	}
 }
 
-// GetID return the metadata by ID.
+// GetID return the metadata by ID. A track ID may have several outcomes, like a failed attempt and a duplicate, so
+// the ingested one is returned first and then the latest recorded.
 func (er *MetaDataRepository) GetID(trackID string) (*domain.MetaData, error) {
 	metadata := &entity.MetaData{}
 
-	err := er.db.DB.Model(&metadata).Where("trackid = ?", trackID).Scan(&metadata).Error
+	err := er.db.DB.Model(&metadata).Where("trackid = ?", trackID).Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:  "outcome = ? DESC, recordedat DESC NULLS LAST, outcome",
+		Vars: []interface{}{string(domain.Ingested)},
+	}}).Limit(1).Scan(&metadata).Error
 	if err != nil {
 		return nil, exceptions.ErrInternalError
 	}
@@ -38,13 +44,12 @@ func (er *MetaDataRepository) GetID(trackID string) (*domain.MetaData, error) {
 }
 
 // Insert records a metadata in the database.
-func (er *MetaDataRepository) Insert(metadata *domain.MetaData) error {
+func (er *MetaDataRepository) Insert(ctx context.Context, metadata *domain.MetaData) error {
 
 	meta := mapper.ToEntityMetaData(metadata)
 
-	err := er.db.DB.Create(&meta).Error
+	err := er.db.Conn(ctx).Create(&meta).Error
 	if err != nil {
-		er.db.DB.Rollback()
 		return err
 	}
 	return nil
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm/clause"
+	"service-worker-sqs-s3-postgres/core/domain"
+	"service-worker-sqs-s3-postgres/core/domain/entity"
+	"service-worker-sqs-s3-postgres/core/domain/exceptions"
+	"service-worker-sqs-s3-postgres/dataproviders/mapper"
+	"service-worker-sqs-s3-postgres/dataproviders/postgres"
+)
+
+type IRejectedRepository interface {
+	GetByTrackID(trackID string) ([]*domain.RejectedRow, error)
+	Insert(ctx context.Context, rows []*domain.RejectedRow) error
+}
+
+// RejectedRepository encapsulates all the data needed to the persistence in the rejected_rows table.
+type RejectedRepository struct {
+	db *postgres.ClientDB
+}
+
+// NewRejectedRepository instance the connection to the postgres.
+func NewRejectedRepository(db *postgres.ClientDB) *RejectedRepository {
+	return &RejectedRepository{
+		db: db,
+	}
+}
+
+// GetByTrackID return the rejected rows of a file by track ID.
+func (er *RejectedRepository) GetByTrackID(trackID string) ([]*domain.RejectedRow, error) {
+	rejected := make([]*entity.RejectedRow, 0)
+
+	err := er.db.DB.Where("trackid = ?", trackID).Order("sheet, line").Find(&rejected).Error
+	if err != nil {
+		return nil, exceptions.ErrInternalError
+	}
+
+	rows := make([]*domain.RejectedRow, 0, len(rejected))
+	for _, r := range rejected {
+		rows = append(rows, mapper.ToDomainRejectedRow(r))
+	}
+	return rows, nil
+}
+
+// Insert records the rejected rows of a file in the database.
+func (er *RejectedRepository) Insert(ctx context.Context, rows []*domain.RejectedRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	rejected := make([]*entity.RejectedRow, 0, len(rows))
+	for _, r := range rows {
+		rejected = append(rejected, mapper.ToEntityRejectedRow(r))
+	}
+
+	err := er.db.Conn(ctx).Clauses(clause.OnConflict{
+		UpdateAll: true,
+	}).Create(&rejected).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
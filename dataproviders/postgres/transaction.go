@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// ITransactor runs functions in a database transaction.
+type ITransactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Transaction runs fn in a transaction, committed when fn returns nil and rolled back otherwise. Repositories called
+// with the context given to fn write in the transaction, and nested calls join the outer transaction.
+func (client *ClientDB) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return client.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Conn returns the connection of the transaction carried by ctx, or else of the client.
+func (client *ClientDB) Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return client.DB.WithContext(ctx)
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"service-worker-sqs-s3-postgres/core/domain"
 	"time"
@@ -21,24 +22,24 @@ func New(logger *zap.SugaredLogger, source domain.Source) (*Processor, error) {
 }
 
 // Start a processor execution.
-func (p *Processor) Start() {
+func (p *Processor) Start(ctx context.Context) {
 	p.logger.Info("Starting processor")
-	stream := p.source.Consume()
+	stream := p.source.Consume(ctx)
 	for event := range stream {
-		go p.handleEvent(event)
+		go p.handleEvent(ctx, event)
 	}
 }
 
 // handleEvent is the entry point to handle consolidate event.
-func (p *Processor) handleEvent(event *domain.Event) {
-	if err := p.source.Processed(event); err != nil {
+func (p *Processor) handleEvent(ctx context.Context, event *domain.Event) {
+	if err := p.source.Processed(ctx, event); err != nil {
 		event.Log.Errorf("Error processing event: %v", err)
 	}
 	elapsed := time.Since(time.Now())
 	event.Log.Infof("Step 7 - Event finished in %dms", elapsed.Milliseconds())
 }
 
-// Stop stops the Processor execution.
-func (p *Processor) Stop() error {
-	return p.source.Close()
+// Stop stops the Processor execution, waiting for in-flight events until ctx is done.
+func (p *Processor) Stop(ctx context.Context) error {
+	return p.source.Close(ctx)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	hfiledata "service-worker-sqs-s3-postgres/entrypoints/controllers/filedata"
 	hmetadata "service-worker-sqs-s3-postgres/entrypoints/controllers/metadata"
+	hrejected "service-worker-sqs-s3-postgres/entrypoints/controllers/rejected"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -23,7 +24,7 @@ type Server struct {
 }
 
 // NewServer creates an instance of Http Server.
-func NewServer(port int, ec *hfiledata.FileDataController, mc *hmetadata.MetaDataController) *Server {
+func NewServer(port int, ec *hfiledata.FileDataController, mc *hmetadata.MetaDataController, rc *hrejected.RejectedController) *Server {
 	e := echo.New()
 
 	// middleware
@@ -41,6 +42,9 @@ func NewServer(port int, ec *hfiledata.FileDataController, mc *hmetadata.MetaDat
 	// metadata
 	path.GET("/s3/metadata/:trackid", mc.GetID)
 
+	// rejected rows
+	path.GET("/s3/rejected/:trackid", rc.GetByTrackID)
+
 	return server
 }
 
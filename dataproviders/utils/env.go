@@ -23,7 +23,7 @@ func GetInt(name string) (int, error) {
 	}
 	intV, err := strconv.Atoi(v)
 	if err != nil {
-		return 0, fmt.Errorf("en var %s must be a number", name)
+		return 0, fmt.Errorf("env var %s must be a number", name)
 	}
 	return intV, nil
 }
@@ -35,3 +35,25 @@ func GetParam(c echo.Context, name string) (string, error) {
 	}
 	return strParam, nil
 }
+
+// GetStringDefault returns the value of the env var name, or def when it is unset or empty.
+func GetStringDefault(name, def string) string {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def
+	}
+	return v
+}
+
+// GetIntDefault returns the value of the env var name as a number, or def when it is unset or empty.
+func GetIntDefault(name string, def int) (int, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return def, nil
+	}
+	intV, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("env var %s must be a number", name)
+	}
+	return intV, nil
+}
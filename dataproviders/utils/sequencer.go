@@ -0,0 +1,16 @@
+package utils
+
+import "strings"
+
+// sequencerWidth is the length sequencers are padded to. S3 only guarantees they grow for the same key, not their
+// length, so shorter ones are padded with leading zeros before comparing.
+const sequencerWidth = 64
+
+// PadSequencer returns the sequencer of an S3 event notification padded to sort as text in the order of the events.
+func PadSequencer(sequencer string) string {
+	sequencer = strings.ToUpper(sequencer)
+	if len(sequencer) >= sequencerWidth {
+		return sequencer
+	}
+	return strings.Repeat("0", sequencerWidth-len(sequencer)) + sequencer
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPadSequencer(t *testing.T) {
+	tests := []struct {
+		name      string
+		sequencer string
+		want      string
+	}{
+		{name: "short", sequencer: "0055AED6DCD90281E5", want: strings.Repeat("0", 46) + "0055AED6DCD90281E5"},
+		{name: "lower case", sequencer: "0a1b", want: strings.Repeat("0", 60) + "0A1B"},
+		{name: "empty", sequencer: "", want: strings.Repeat("0", 64)},
+		{name: "as long as the width", sequencer: strings.Repeat("F", 64), want: strings.Repeat("F", 64)},
+		{name: "longer than the width", sequencer: strings.Repeat("f", 70), want: strings.Repeat("F", 70)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PadSequencer(tt.sequencer); got != tt.want {
+				t.Errorf("PadSequencer(%q) = %q, want %q", tt.sequencer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadSequencerOrder(t *testing.T) {
+	tests := []struct {
+		name           string
+		earlier, later string
+	}{
+		{name: "same length", earlier: "0055AED6DCD90281E5", later: "0055AED6DCD90281E6"},
+		{name: "later is longer", earlier: "FFFF", later: "10000"},
+		{name: "later is longer with leading zeros", earlier: "0055AED6DCD90281E5", later: "0055AED6DCD90281E500"},
+		{name: "different case", earlier: "00a0", later: "00B0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			earlier, later := PadSequencer(tt.earlier), PadSequencer(tt.later)
+			if earlier >= later {
+				t.Errorf("PadSequencer(%q) = %q doesn't sort before PadSequencer(%q) = %q", tt.earlier, earlier, tt.later, later)
+			}
+		})
+	}
+}
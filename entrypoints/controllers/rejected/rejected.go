@@ -0,0 +1,34 @@
+package rejected
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"service-worker-sqs-s3-postgres/core/domain/exceptions"
+	cases "service-worker-sqs-s3-postgres/core/usecases/rejected"
+	env "service-worker-sqs-s3-postgres/dataproviders/utils"
+)
+
+// RejectedController encapsulates all the data necessary for the implementation of the RejectedService.
+type RejectedController struct {
+	rejectedUseCases cases.IRejectedCaseUses
+}
+
+// NewRejectedController instantiate a new rejected rows controller.
+func NewRejectedController(rs cases.IRejectedCaseUses) *RejectedController {
+	return &RejectedController{
+		rejectedUseCases: rs,
+	}
+}
+
+// GetByTrackID return the rejected rows of a file by track ID [rejectedUseCases.GetByTrackID].
+func (rc *RejectedController) GetByTrackID(c echo.Context) error {
+	ID, err := env.GetParam(c, "trackid")
+	if err != nil {
+		return exceptions.NewError(http.StatusBadRequest, err)
+	}
+	rows, err := rc.rejectedUseCases.GetByTrackID(ID)
+	if err != nil {
+		return exceptions.HandleServiceError(err)
+	}
+	return c.JSON(http.StatusOK, rows)
+}